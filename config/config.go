@@ -1,9 +1,12 @@
 package config
 
 import (
+	"azuserver/utils"
 	"fmt"
 	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -15,17 +18,89 @@ const (
 	// Oricon.
 	DomainOricon  = "www.oricon.co.jp"
 	OriconRankUrl = "https://www.oricon.co.jp/rank/"
+
+	DefaultDataDir = "./data"
+
+	// YouTube 默认使用英文界面，保证不同运行环境中解析的文本一致
+	DefaultYouTubeHL = "en"
+	DefaultYouTubeGL = "US"
 )
 
 type Config struct {
-	DiscordChatWebhookUrl string `yaml:"chat_webhook"`
-	DiscordSysWebhookUrl  string `yaml:"system_webhook"`
-	YouTubeDefaultUserID  string `yaml:"youtube_default_user_id"`
-	BilibiliDefaultUID    string `yaml:"bilibili_default_uid"`
+	DiscordChatWebhookUrl string                   `yaml:"chat_webhook"`
+	DiscordSysWebhookUrl  string                   `yaml:"system_webhook"`
+	YouTubeDefaultUserID  string                   `yaml:"youtube_default_user_id"`
+	BilibiliDefaultUID    string                   `yaml:"bilibili_default_uid"`
+	BilibiliVideoOrder    string                   `yaml:"bilibili_video_order"`
+	BilibiliUserSections  []string                 `yaml:"bilibili_user_sections"`
+	BilibiliLiveUIDs      []string                 `yaml:"bilibili_live_uids"`
+	BilibiliDynamicUIDs   []string                 `yaml:"bilibili_dynamic_uids"`
+	BilibiliRankingBoards []string                 `yaml:"bilibili_ranking_boards"`
+	BilibiliCookie        BilibiliCookieConfig     `yaml:"bilibili_cookie"`
+	YouTubeWatchChannels  []string                 `yaml:"youtube_watch_channels"`
+	YouTubeLiveChannels   []string                 `yaml:"youtube_live_channels"`
+	YouTubeUserSections   []string                 `yaml:"youtube_user_sections"`
+	YouTubePlaylists      []string                 `yaml:"youtube_playlists"`
+	YouTubeLocale         YouTubeLocaleConfig      `yaml:"youtube_locale"`
+	DataDir               string                   `yaml:"data_dir"`
+	BrowserPath           string                   `yaml:"browser_path"`
+	Feed                  FeedConfig               `yaml:"feed"`
+	TemplatesDir          string                   `yaml:"templates_dir"`
+	Timezone              string                   `yaml:"timezone"`
+	Sinks                 map[string]SinkConfig    `yaml:"sinks"`
+	Routes                map[string][]RouteConfig `yaml:"routes"`
+}
+
+// FeedConfig Atom 订阅输出配置，Dir 为空时不生成订阅
+type FeedConfig struct {
+	Dir     string            `yaml:"dir"`
+	BaseURL string            `yaml:"base_url"`
+	Mode    string            `yaml:"mode"`
+	Tasks   map[string]string `yaml:"tasks"`
+}
+
+// BilibiliCookieConfig Bilibili 登录 Cookie，File 为 Netscape cookies.txt 或 "name=value; ..." 格式的文件，
+// 单独配置的值覆盖文件中的同名 Cookie
+type BilibiliCookieConfig struct {
+	File     string `yaml:"file"`
+	SESSDATA string `yaml:"sessdata"`
+	BiliJct  string `yaml:"bili_jct"`
+	Buvid3   string `yaml:"buvid3"`
+}
+
+// YouTubeLocaleConfig YouTube 请求的界面语言（hl）和地区（gl），Tasks 按任务覆盖
+type YouTubeLocaleConfig struct {
+	HL    string                  `yaml:"hl"`
+	GL    string                  `yaml:"gl"`
+	Tasks map[string]LocaleConfig `yaml:"tasks"`
+}
+
+// LocaleConfig 单个任务的界面语言和地区，为空的字段使用全局配置
+type LocaleConfig struct {
+	HL string `yaml:"hl"`
+	GL string `yaml:"gl"`
+}
+
+// SinkConfig 命名的输出目标，可在多个路由中复用
+type SinkConfig struct {
+	Webhook   string `yaml:"webhook"`
+	Username  string `yaml:"username"`
+	AvatarURL string `yaml:"avatar_url"`
+}
+
+// RouteConfig 路由中的一个输出目标，Sink 和 Webhook 二选一（同时设置时该目标无效），Username/AvatarURL 覆盖 Sink 中的值
+type RouteConfig struct {
+	Sink      string `yaml:"sink"`
+	Webhook   string `yaml:"webhook"`
+	Username  string `yaml:"username"`
+	AvatarURL string `yaml:"avatar_url"`
 }
 
 var (
 	appConfig Config
+
+	location     *time.Location
+	locationOnce sync.Once
 )
 
 func GetDiscordChatWebhookUrl() string {
@@ -44,6 +119,142 @@ func GetBilibiliDefaultUID() string {
 	return appConfig.BilibiliDefaultUID
 }
 
+// GetBilibiliVideoOrder 返回 bilibili_user 报告中最新视频的排序方式（pubdate、click、stow）
+func GetBilibiliVideoOrder() string {
+	return appConfig.BilibiliVideoOrder
+}
+
+// GetBilibiliUserSections 返回 bilibili_user 报告中每个视频附加的统计（comments、danmaku）
+func GetBilibiliUserSections() []string {
+	return appConfig.BilibiliUserSections
+}
+
+// GetBilibiliLiveUIDs 返回 bilibili_live 任务监视直播间的用户 UID 列表
+func GetBilibiliLiveUIDs() []string {
+	return appConfig.BilibiliLiveUIDs
+}
+
+// GetBilibiliDynamicUIDs 返回 bilibili_dynamic 任务监视动态的用户 UID 列表
+func GetBilibiliDynamicUIDs() []string {
+	return appConfig.BilibiliDynamicUIDs
+}
+
+// GetBilibiliRankingBoards 返回 bilibili_ranking 任务发送的排行榜（all、anime、music、game、vocaloid、weekly）
+func GetBilibiliRankingBoards() []string {
+	return appConfig.BilibiliRankingBoards
+}
+
+// GetBilibiliCookie 返回 Bilibili 请求和无头浏览器共用的登录 Cookie 配置
+func GetBilibiliCookie() BilibiliCookieConfig {
+	return appConfig.BilibiliCookie
+}
+
+// GetYouTubeWatchChannels 返回 youtube_watch 任务监视的频道列表
+func GetYouTubeWatchChannels() []string {
+	return appConfig.YouTubeWatchChannels
+}
+
+// GetYouTubeLiveChannels 返回 youtube_live 任务监视的频道列表
+func GetYouTubeLiveChannels() []string {
+	return appConfig.YouTubeLiveChannels
+}
+
+// GetYouTubeUserSections 返回 youtube_user 报告中列出的频道标签页（videos、shorts、streams）
+func GetYouTubeUserSections() []string {
+	return appConfig.YouTubeUserSections
+}
+
+// GetYouTubePlaylists 返回 youtube_playlist 任务跟踪的播放列表
+func GetYouTubePlaylists() []string {
+	return appConfig.YouTubePlaylists
+}
+
+// GetDataDir 返回历史记录等本地数据的存放目录
+func GetDataDir() string {
+	if appConfig.DataDir == "" {
+		return DefaultDataDir
+	}
+	return appConfig.DataDir
+}
+
+// GetBrowserPath 返回无头浏览器的可执行文件路径，为空时自动查找本机的 Chrome/Chromium
+func GetBrowserPath() string {
+	return appConfig.BrowserPath
+}
+
+func GetFeedDir() string {
+	return appConfig.Feed.Dir
+}
+
+func GetFeedBaseURL() string {
+	return appConfig.Feed.BaseURL
+}
+
+// GetFeedMode 返回任务的订阅生成方式（item 或 run），任务未单独配置时使用全局配置
+func GetFeedMode(task string) string {
+	if mode, ok := appConfig.Feed.Tasks[task]; ok && mode != "" {
+		return mode
+	}
+	return appConfig.Feed.Mode
+}
+
+// GetYouTubeLocale 返回任务请求 YouTube 时使用的 hl 和 gl，依次取任务配置、全局配置和默认值
+func GetYouTubeLocale(task string) (hl string, gl string) {
+	hl, gl = appConfig.YouTubeLocale.HL, appConfig.YouTubeLocale.GL
+	if override, ok := appConfig.YouTubeLocale.Tasks[task]; ok {
+		if override.HL != "" {
+			hl = override.HL
+		}
+		if override.GL != "" {
+			gl = override.GL
+		}
+	}
+	if hl == "" {
+		hl = DefaultYouTubeHL
+	}
+	if gl == "" {
+		gl = DefaultYouTubeGL
+	}
+	return hl, gl
+}
+
+// GetTemplatesDir 返回自定义消息模板目录，为空时只使用内置模板
+func GetTemplatesDir() string {
+	return appConfig.TemplatesDir
+}
+
+// GetLocation 返回消息中显示时间所用的时区（如 "Asia/Tokyo"），未配置或无效时使用本地时区
+func GetLocation() *time.Location {
+	locationOnce.Do(func() {
+		location = time.Local
+		if appConfig.Timezone == "" {
+			return
+		}
+		loc, err := time.LoadLocation(appConfig.Timezone)
+		if err != nil {
+			slog.Warn("invalid timezone, using local time", "timezone", appConfig.Timezone, "error", err)
+			return
+		}
+		location = loc
+	})
+	return location
+}
+
+// GetRoutes 返回任务的路由，优先匹配 "task:variant"，其次匹配 "task"，都未配置时返回 nil
+func GetRoutes(task string, variant string) []RouteConfig {
+	if variant != "" {
+		if routes, ok := appConfig.Routes[task+":"+variant]; ok {
+			return routes
+		}
+	}
+	return appConfig.Routes[task]
+}
+
+func GetSink(name string) (SinkConfig, bool) {
+	sink, ok := appConfig.Sinks[name]
+	return sink, ok
+}
+
 func LoadConfig() error {
 	// from local file.
 	if _, err := os.Stat(YamlConfigPath); !os.IsNotExist(err) {
@@ -68,6 +279,27 @@ func LoadConfig() error {
 	appConfig.DiscordSysWebhookUrl = os.Getenv("DISCORD_SYS_WEBHOOK_URL")
 	appConfig.YouTubeDefaultUserID = os.Getenv("YOUTUBE_DEFAULT_USER_ID")
 	appConfig.BilibiliDefaultUID = os.Getenv("BILIBILI_DEFAULT_UID")
+	appConfig.BilibiliVideoOrder = os.Getenv("BILIBILI_VIDEO_ORDER")
+	appConfig.BilibiliUserSections = utils.SplitList(os.Getenv("BILIBILI_USER_SECTIONS"))
+	appConfig.BilibiliLiveUIDs = utils.SplitList(os.Getenv("BILIBILI_LIVE_UIDS"))
+	appConfig.BilibiliDynamicUIDs = utils.SplitList(os.Getenv("BILIBILI_DYNAMIC_UIDS"))
+	appConfig.BilibiliRankingBoards = utils.SplitList(os.Getenv("BILIBILI_RANKING_BOARDS"))
+	appConfig.BilibiliCookie.File = os.Getenv("BILIBILI_COOKIE_FILE")
+	appConfig.BilibiliCookie.SESSDATA = os.Getenv("BILIBILI_SESSDATA")
+	appConfig.BilibiliCookie.BiliJct = os.Getenv("BILIBILI_JCT")
+	appConfig.BilibiliCookie.Buvid3 = os.Getenv("BILIBILI_BUVID3")
+	appConfig.YouTubeWatchChannels = utils.SplitList(os.Getenv("YOUTUBE_WATCH_CHANNELS"))
+	appConfig.YouTubeLiveChannels = utils.SplitList(os.Getenv("YOUTUBE_LIVE_CHANNELS"))
+	appConfig.YouTubeUserSections = utils.SplitList(os.Getenv("YOUTUBE_USER_SECTIONS"))
+	appConfig.YouTubePlaylists = utils.SplitList(os.Getenv("YOUTUBE_PLAYLISTS"))
+	appConfig.YouTubeLocale.HL = os.Getenv("YOUTUBE_HL")
+	appConfig.YouTubeLocale.GL = os.Getenv("YOUTUBE_GL")
+	appConfig.DataDir = os.Getenv("DATA_DIR")
+	appConfig.Feed.Dir = os.Getenv("FEED_DIR")
+	appConfig.Feed.BaseURL = os.Getenv("FEED_BASE_URL")
+	appConfig.Feed.Mode = os.Getenv("FEED_MODE")
+	appConfig.TemplatesDir = os.Getenv("TEMPLATES_DIR")
+	appConfig.Timezone = os.Getenv("TIMEZONE")
 	slog.Info("loading configurations from shell env")
 
 	return nil
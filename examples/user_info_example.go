@@ -2,6 +2,7 @@ package main
 
 import (
 	"azuserver/service"
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
@@ -43,7 +44,7 @@ func main() {
 			"userID": userID,
 		}
 		
-		if err := service.RunServiceWithParams("youtube_user", params); err != nil {
+		if err := service.RunServiceWithParams(context.Background(), "youtube_user", params); err != nil {
 			log.Fatalf("Failed to get YouTube user info: %v", err)
 		}
 		
@@ -54,7 +55,7 @@ func main() {
 			"uid": userID,
 		}
 		
-		if err := service.RunServiceWithParams("bilibili_user", params); err != nil {
+		if err := service.RunServiceWithParams(context.Background(), "bilibili_user", params); err != nil {
 			log.Fatalf("Failed to get Bilibili user info: %v", err)
 		}
 		
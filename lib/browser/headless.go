@@ -2,18 +2,40 @@ package browser
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
 	"time"
 
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
+	"github.com/pkg/errors"
 )
 
+// ErrBrowserUnavailable 本机没有可用的 Chrome/Chromium 时返回的错误，调用方可用 errors.Is 判断后跳过浏览器方法
+var ErrBrowserUnavailable = errors.New("headless browser unavailable")
+
+// execCandidates 按顺序查找的浏览器可执行文件
+var execCandidates = []string{
+	"headless-shell",
+	"chromium",
+	"chromium-browser",
+	"google-chrome",
+	"google-chrome-stable",
+	"chrome",
+	"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	"/Applications/Chromium.app/Contents/MacOS/Chromium",
+	`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+	`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+}
+
 // HeadlessBrowser 无头浏览器封装
 type HeadlessBrowser struct {
-	ctx    context.Context
-	cancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	allocCancel context.CancelFunc
 }
 
 // PageResult 页面结果
@@ -25,10 +47,37 @@ type PageResult struct {
 	Error     error                  `json:"error"`
 }
 
-// NewHeadlessBrowser 创建新的无头浏览器实例
-func NewHeadlessBrowser() *HeadlessBrowser {
+// FindExecPath 查找本机的 Chrome/Chromium，execPath 非空时只检查该路径，其次检查 CHROME_PATH 环境变量
+func FindExecPath(execPath string) (string, error) {
+	if execPath == "" {
+		execPath = os.Getenv("CHROME_PATH")
+	}
+	if execPath != "" {
+		path, err := exec.LookPath(execPath)
+		if err != nil {
+			return "", errors.Wrapf(ErrBrowserUnavailable, "browser %q not found", execPath)
+		}
+		return path, nil
+	}
+	for _, candidate := range execCandidates {
+		if path, err := exec.LookPath(candidate); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.Wrap(ErrBrowserUnavailable, "no Chrome or Chromium found, set CHROME_PATH to enable")
+}
+
+// NewHeadlessBrowser 启动无头浏览器，execPath 为空时自动查找本机的浏览器；
+// 找不到浏览器时返回包装了 ErrBrowserUnavailable 的错误
+func NewHeadlessBrowser(ctx context.Context, execPath string) (*HeadlessBrowser, error) {
+	path, err := FindExecPath(execPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建 Chrome 上下文
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(path),
 		chromedp.Flag("headless", true),
 		chromedp.Flag("disable-gpu", true),
 		chromedp.Flag("no-sandbox", true),
@@ -39,13 +88,25 @@ func NewHeadlessBrowser() *HeadlessBrowser {
 		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"),
 	)
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	ctx, _ := chromedp.NewContext(allocCtx)
+	allocCtx, allocCancel := chromedp.NewExecAllocator(ctx, opts...)
+	browserCtx, cancel := chromedp.NewContext(allocCtx)
 
-	return &HeadlessBrowser{
-		ctx:    ctx,
-		cancel: cancel,
+	// 先启动浏览器，可执行文件存在但无法运行时同样视为不可用
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancel()
+		allocCancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, errors.Wrapf(ErrBrowserUnavailable, "failed to start %s: %v", path, err)
 	}
+	slog.Debug("Started headless browser", "path", path)
+
+	return &HeadlessBrowser{
+		ctx:         browserCtx,
+		cancel:      cancel,
+		allocCancel: allocCancel,
+	}, nil
 }
 
 // Close 关闭浏览器
@@ -53,6 +114,30 @@ func (hb *HeadlessBrowser) Close() {
 	if hb.cancel != nil {
 		hb.cancel()
 	}
+	if hb.allocCancel != nil {
+		hb.allocCancel()
+	}
+}
+
+// SetCookies 为 domain（如 ".bilibili.com"）设置 Cookie，之后加载的页面都会带上
+func (hb *HeadlessBrowser) SetCookies(cookies []*http.Cookie, domain string) error {
+	if len(cookies) == 0 {
+		return nil
+	}
+	return chromedp.Run(hb.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, cookie := range cookies {
+			err := network.SetCookie(cookie.Name, cookie.Value).
+				WithDomain(domain).
+				WithPath("/").
+				WithSecure(cookie.Secure).
+				WithHTTPOnly(cookie.HttpOnly).
+				Do(ctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to set cookie %s", cookie.Name)
+			}
+		}
+		return nil
+	}))
 }
 
 // LoadPage 加载页面并等待JavaScript执行
@@ -226,22 +311,26 @@ func (hb *HeadlessBrowser) ExtractBilibiliStats(uid string) (map[string]interfac
             }
         });
         
-        // 尝试其他可能的选择器
-        var statSelectors = [
-            '[class*="fans"]',
-            '[class*="follow"]', 
-            '[class*="stat"]',
-            '[data-v-*][class*="num"]'
-        ];
-        
-        statSelectors.forEach(function(selector) {
-            var elements = document.querySelectorAll(selector);
-            elements.forEach(function(el) {
-                var text = el.textContent.trim();
-                if (text.match(/\d/) && text.length < 20) {
-                    console.log('Found potential stat:', selector, text);
-                }
-            });
+        // 新版空间页面的统计栏
+        var navItems = document.querySelectorAll('.nav-statistics__item');
+        navItems.forEach(function(item) {
+            var numEl = item.querySelector('.nav-statistics__item-num');
+            var textEl = item.querySelector('.nav-statistics__item-text');
+            if (!numEl || !textEl) {
+                return;
+            }
+            // title 属性中是未缩写的完整数字
+            var value = parseCount(numEl.getAttribute('title') || numEl.textContent);
+            var label = textEl.textContent;
+            if (label.includes('粉丝')) {
+                stats.follower = value;
+            } else if (label.includes('关注')) {
+                stats.following = value;
+            } else if (label.includes('获赞')) {
+                stats.like = value;
+            } else if (label.includes('播放')) {
+                stats.play = value;
+            }
         });
         
         // 解析计数的辅助函数
@@ -256,16 +345,11 @@ func (hb *HeadlessBrowser) ExtractBilibiliStats(uid string) (map[string]interfac
                 var num = parseFloat(countStr.replace('亿', ''));
                 return Math.floor(num * 100000000);
             } else {
-                var num = parseInt(countStr.replace(/[^\d]/g, ''));
+                var num = parseInt(countStr.replace(/[^\d]/g, ''), 10);
                 return isNaN(num) ? 0 : num;
             }
         }
         
-        // 尝试等待异步数据加载
-        setTimeout(function() {
-            // 再次尝试获取数据
-        }, 2000);
-        
     } catch (e) {
         console.error('Error extracting stats:', e);
         stats.error = e.toString();
@@ -280,8 +364,8 @@ func (hb *HeadlessBrowser) ExtractBilibiliStats(uid string) (map[string]interfac
 		return nil, err
 	}
 
-	if result.JSResults["main"] != nil {
-		return result.JSResults["main"].(map[string]interface{}), nil
+	if stats, ok := result.JSResults["main"].(map[string]interface{}); ok {
+		return stats, nil
 	}
 
 	return make(map[string]interface{}), nil
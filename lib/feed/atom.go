@@ -0,0 +1,221 @@
+package feed
+
+import (
+	"azuserver/lib/history"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Mode 订阅条目的生成方式
+type Mode string
+
+const (
+	// ModeItem 每个排行条目一条订阅，同一条目再次上榜时只更新时间
+	ModeItem Mode = "item"
+	// ModeRun 每次运行一条订阅
+	ModeRun Mode = "run"
+)
+
+// DefaultMaxEntries 单个订阅文件中保留的最大条目数
+const DefaultMaxEntries = 50
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// feedEpoch 订阅及其条目ID中的日期，固定取值以免历史记录裁剪后ID变化
+var feedEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options 生成订阅时使用的参数
+type Options struct {
+	Title      string
+	BaseURL    string
+	Mode       Mode
+	MaxEntries int
+}
+
+// Feed Atom 订阅
+type Feed struct {
+	XMLName xml.Name `xml:"feed"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []Link   `xml:"link,omitempty"`
+	Author  *Person  `xml:"author,omitempty"`
+	Entries []Entry  `xml:"entry"`
+}
+
+// Link Atom 链接
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// Person Atom 作者
+type Person struct {
+	Name string `xml:"name"`
+}
+
+// Text Atom 文本内容
+type Text struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+// Entry Atom 条目
+type Entry struct {
+	ID        string `xml:"id"`
+	Title     string `xml:"title"`
+	Updated   string `xml:"updated"`
+	Published string `xml:"published,omitempty"`
+	Links     []Link `xml:"link,omitempty"`
+	Summary   *Text  `xml:"summary,omitempty"`
+	Content   *Text  `xml:"content,omitempty"`
+}
+
+// FileName 返回存储键对应的订阅文件名
+func FileName(key string) string {
+	return key + ".atom"
+}
+
+// tagURI 生成 RFC 4151 格式的稳定ID
+func tagURI(authority string, date time.Time, specific string) string {
+	return fmt.Sprintf("tag:%s,%s:%s", authority, date.UTC().Format("2006-01-02"), specific)
+}
+
+func authorityFromBaseURL(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "azutv.local"
+}
+
+// Build 根据历史记录生成订阅，条目ID只依赖存储键和条目ID
+func Build(key string, runs []history.Run, opts Options) *Feed {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+	authority := authorityFromBaseURL(opts.BaseURL)
+
+	f := &Feed{
+		XMLNS:  atomNamespace,
+		Title:  opts.Title,
+		ID:     tagURI(authority, feedEpoch, key),
+		Author: &Person{Name: "azutv"},
+	}
+	if opts.BaseURL != "" {
+		f.Links = append(f.Links, Link{
+			Href: strings.TrimSuffix(opts.BaseURL, "/") + "/" + FileName(key),
+			Rel:  "self",
+			Type: "application/atom+xml",
+		})
+	}
+
+	if opts.Mode == ModeRun {
+		f.Entries = buildRunEntries(key, authority, runs)
+	} else {
+		f.Entries = buildItemEntries(key, authority, runs)
+	}
+	if len(f.Entries) > opts.MaxEntries {
+		f.Entries = f.Entries[:opts.MaxEntries]
+	}
+
+	if len(f.Entries) > 0 {
+		f.Updated = f.Entries[0].Updated
+	} else {
+		f.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+	return f
+}
+
+func buildItemEntries(key string, authority string, runs []history.Run) []Entry {
+	firstSeen := history.FirstSeen(runs)
+	latest := make(map[string]history.Item)
+	lastSeen := make(map[string]time.Time)
+	for _, run := range runs {
+		for _, item := range run.Items {
+			latest[item.ID] = item
+			lastSeen[item.ID] = run.Time
+		}
+	}
+
+	ids := make([]string, 0, len(latest))
+	for id := range latest {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if !lastSeen[ids[i]].Equal(lastSeen[ids[j]]) {
+			return lastSeen[ids[i]].After(lastSeen[ids[j]])
+		}
+		return latest[ids[i]].Rank < latest[ids[j]].Rank
+	})
+
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		item := latest[id]
+		entry := Entry{
+			// 首次出现时间会随历史记录裁剪而后移，ID 中固定使用 feedEpoch
+			ID:        tagURI(authority, feedEpoch, key+"/"+id),
+			Title:     item.Title,
+			Updated:   lastSeen[id].UTC().Format(time.RFC3339),
+			Published: firstSeen[id].UTC().Format(time.RFC3339),
+		}
+		if item.Link != "" {
+			entry.Links = []Link{{Href: item.Link, Rel: "alternate"}}
+		}
+		if item.Summary != "" {
+			entry.Summary = &Text{Type: "text", Body: item.Summary}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func buildRunEntries(key string, authority string, runs []history.Run) []Entry {
+	entries := make([]Entry, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		stamp := run.Time.UTC().Format(time.RFC3339)
+		entry := Entry{
+			ID:        tagURI(authority, run.Time, key+"/run/"+run.Time.UTC().Format("20060102T150405Z")),
+			Title:     fmt.Sprintf("%s %s", key, run.Time.UTC().Format("2006-01-02 15:04")),
+			Updated:   stamp,
+			Published: stamp,
+		}
+		if len(run.Messages) > 0 {
+			entry.Content = &Text{Type: "text", Body: strings.Join(run.Messages, "\n")}
+		} else {
+			var lines []string
+			for _, item := range run.Items {
+				lines = append(lines, fmt.Sprintf("%d. %s %s", item.Rank, item.Title, item.Link))
+			}
+			entry.Content = &Text{Type: "text", Body: strings.Join(lines, "\n")}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Write 将订阅写入 dir 目录下
+func Write(dir string, key string, f *Feed) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create feed directory")
+	}
+	data, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode feed %q", key)
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(filepath.Join(dir, FileName(key)), data, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write feed %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package feed
+
+import (
+	"azuserver/lib/history"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func entryIDs(f *Feed) map[string]string {
+	ids := make(map[string]string)
+	for _, entry := range f.Entries {
+		ids[entry.Title] = entry.ID
+	}
+	return ids
+}
+
+func TestBuildEntryIDsStableAfterTrimming(t *testing.T) {
+	store := history.NewStore(t.TempDir())
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	item := history.Item{ID: "BV1", Title: "kept", Link: "https://example.com/BV1"}
+
+	appendRun := func(i int, items ...history.Item) []history.Run {
+		runs, err := store.Append(history.Run{Task: "bilibili_ranking", Variant: "all", Time: start.Add(time.Duration(i) * time.Hour), Items: items})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runs
+	}
+	opts := Options{Title: "test", BaseURL: "https://example.com/feeds", Mode: ModeItem}
+	key := history.Key("bilibili_ranking", "all")
+
+	runs := appendRun(0, item)
+	before := entryIDs(Build(key, runs, opts))
+
+	// 超过 MaxRuns 后第一次运行被裁剪，条目的首次出现时间随之后移
+	for i := 1; i <= history.MaxRuns; i++ {
+		runs = appendRun(i, history.Item{ID: fmt.Sprintf("other%d", i%3), Title: fmt.Sprintf("other%d", i%3)})
+	}
+	runs = appendRun(history.MaxRuns+1, item)
+	if len(runs) != history.MaxRuns || !runs[0].Time.After(start) {
+		t.Fatalf("history was not trimmed: %d runs, first at %s", len(runs), runs[0].Time)
+	}
+
+	after := entryIDs(Build(key, runs, opts))
+	if before["kept"] == "" || after["kept"] != before["kept"] {
+		t.Errorf("entry ID changed after trimming: %q -> %q", before["kept"], after["kept"])
+	}
+
+	rebuilt := entryIDs(Build(key, runs, opts))
+	for title, id := range after {
+		if rebuilt[title] != id {
+			t.Errorf("entry ID of %q changed across rebuilds: %q -> %q", title, id, rebuilt[title])
+		}
+	}
+}
+
+func TestBuildRunEntryIDsStable(t *testing.T) {
+	runs := []history.Run{
+		{Task: "oricon_ranking", Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Task: "oricon_ranking", Time: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	opts := Options{Title: "test", Mode: ModeRun}
+	first := Build("oricon_ranking", runs, opts)
+	// 旧的运行被裁剪后，剩余运行的条目ID不变
+	trimmed := Build("oricon_ranking", runs[1:], opts)
+	if len(first.Entries) != 2 || len(trimmed.Entries) != 1 || first.Entries[0].ID != trimmed.Entries[0].ID {
+		t.Errorf("run entry IDs changed: %+v -> %+v", first.Entries, trimmed.Entries)
+	}
+	if first.ID != trimmed.ID {
+		t.Errorf("feed ID changed: %q -> %q", first.ID, trimmed.ID)
+	}
+}
@@ -0,0 +1,155 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxRuns 每个任务最多保留的运行记录数
+const MaxRuns = 1000
+
+// Item 单次运行中的一个条目（排行项、视频等）
+type Item struct {
+	// ID 在多次运行之间保持不变，用于去重和生成稳定的订阅条目ID
+	ID      string          `json:"id"`
+	Rank    int             `json:"rank,omitempty"`
+	Title   string          `json:"title"`
+	Link    string          `json:"link,omitempty"`
+	Summary string          `json:"summary,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Run 一次任务运行的记录
+type Run struct {
+	Task     string    `json:"task"`
+	Variant  string    `json:"variant,omitempty"`
+	Time     time.Time `json:"time"`
+	Items    []Item    `json:"items"`
+	Messages []string  `json:"messages,omitempty"`
+}
+
+// Store 基于本地 JSON 文件的历史记录存储
+type Store struct {
+	dir string
+}
+
+// NewStore 创建以 dir 为根目录的历史记录存储
+func NewStore(dir string) *Store {
+	return &Store{dir: filepath.Join(dir, "history")}
+}
+
+// Key 返回任务及其变体对应的存储键。变体中含有文件名不允许的字符时替换为 "_" 并附加原始变体的
+// 短哈希，"a/b" 与 "a_b" 不会得到同一个键；sanitize 会替换 "."，哈希前的 "." 不会出现在其他键中
+func Key(task string, variant string) string {
+	if variant == "" {
+		return task
+	}
+	safe := sanitize(variant)
+	if safe == variant {
+		return task + "-" + variant
+	}
+	sum := sha256.Sum256([]byte(variant))
+	return task + "-" + safe + "." + hex.EncodeToString(sum[:4])
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Load 读取某个任务的全部运行记录，按时间升序排列
+func (s *Store) Load(key string) ([]Run, error) {
+	data, err := os.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read history %q", key)
+	}
+
+	var runs []Run
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode history %q", key)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Time.Before(runs[j].Time)
+	})
+	return runs, nil
+}
+
+// Append 追加一次运行记录，并返回追加后的全部记录
+func (s *Store) Append(run Run) ([]Run, error) {
+	key := Key(run.Task, run.Variant)
+	runs, err := s.Load(key)
+	if err != nil {
+		return nil, err
+	}
+	runs = append(runs, run)
+	if len(runs) > MaxRuns {
+		runs = runs[len(runs)-MaxRuns:]
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create history directory")
+	}
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode history %q", key)
+	}
+	if err := os.WriteFile(s.path(key), data, 0o644); err != nil {
+		return nil, errors.Wrapf(err, "failed to write history %q", key)
+	}
+	return runs, nil
+}
+
+// Keys 列出已有历史记录的全部存储键
+func (s *Store) Keys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list history directory")
+	}
+
+	var keys []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// FirstSeen 返回每个条目ID第一次出现的时间
+func FirstSeen(runs []Run) map[string]time.Time {
+	seen := make(map[string]time.Time)
+	for _, run := range runs {
+		for _, item := range run.Items {
+			if t, ok := seen[item.ID]; !ok || run.Time.Before(t) {
+				seen[item.ID] = run.Time
+			}
+		}
+	}
+	return seen
+}
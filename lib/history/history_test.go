@@ -0,0 +1,34 @@
+package history
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		task, variant string
+		want          string
+	}{
+		{"oricon_ranking", "", "oricon_ranking"},
+		{"bilibili_user", "946974", "bilibili_user-946974"},
+		{"youtube_user", "UCX6OQ3DkcsbYNE6H8uQQuVA", "youtube_user-UCX6OQ3DkcsbYNE6H8uQQuVA"},
+		{"bilibili_ranking", "all", "bilibili_ranking-all"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.task, tt.variant); got != tt.want {
+			t.Errorf("Key(%q, %q) = %q, want %q", tt.task, tt.variant, got, tt.want)
+		}
+	}
+
+	// 替换字符后相同的变体不能得到同一个键
+	variants := []string{"a_b", "a/b", "a b", "a.b", "a:b", "go weekly", "go_weekly", "go/weekly", "a_b.00000000"}
+	seen := make(map[string]string)
+	for _, variant := range variants {
+		key := Key("github_trending", variant)
+		if other, ok := seen[key]; ok {
+			t.Errorf("Key for %q and %q are both %q", variant, other, key)
+		}
+		seen[key] = variant
+		if key != Key("github_trending", variant) {
+			t.Errorf("Key for %q is not deterministic", variant)
+		}
+	}
+}
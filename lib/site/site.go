@@ -0,0 +1,308 @@
+package site
+
+import (
+	"azuserver/lib/history"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Format 站点输出格式
+type Format string
+
+const (
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Options 生成站点时使用的参数
+type Options struct {
+	OutDir   string
+	Format   Format
+	Title    string
+	Location *time.Location
+}
+
+// Appearance 条目在某次运行中出现的记录
+type Appearance struct {
+	Time    time.Time
+	Date    string
+	DayPath string
+	Rank    int
+}
+
+// ItemPage 单个条目的页面数据，汇总该条目在所有运行中的出现记录
+type ItemPage struct {
+	ID          string
+	Path        string
+	Title       string
+	Link        string
+	Summary     string
+	Fields      map[string]any
+	Appearances []Appearance
+	BestRank    int
+}
+
+// Count 条目出现的次数
+func (p *ItemPage) Count() int {
+	return len(p.Appearances)
+}
+
+// RunView 某次运行的页面数据
+type RunView struct {
+	Time  time.Time
+	Items []RunItem
+}
+
+// RunItem 某次运行中的一个条目
+type RunItem struct {
+	history.Item
+	Fields   map[string]any
+	ItemPath string
+}
+
+// DayPage 某个任务某一天的页面数据
+type DayPage struct {
+	Date string
+	Path string
+	Runs []RunView
+}
+
+// TaskPage 某个任务（含变体）的页面数据
+type TaskPage struct {
+	Key     string
+	Task    string
+	Variant string
+	Path    string
+	Days    []*DayPage
+	Items   []*ItemPage
+}
+
+type pageData struct {
+	Title string
+	Root  string
+	Task  *TaskPage
+	Day   *DayPage
+	Item  *ItemPage
+	Tasks []*TaskPage
+}
+
+type renderer interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// Generate 读取全部历史记录并在 OutDir 下生成静态站点
+func Generate(store *history.Store, opts Options) error {
+	if opts.Format == "" {
+		opts.Format = FormatHTML
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.Title == "" {
+		opts.Title = "azutv archive"
+	}
+
+	r, ext, err := newRenderer(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	keys, err := store.Keys()
+	if err != nil {
+		return err
+	}
+
+	var tasks []*TaskPage
+	for _, key := range keys {
+		runs, err := store.Load(key)
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		tasks = append(tasks, buildTaskPage(key, runs, ext, opts.Location))
+	}
+
+	write := func(path string, name string, data pageData) error {
+		full := filepath.Join(opts.OutDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return errors.Wrapf(err, "failed to create directory for %s", path)
+		}
+		f, err := os.Create(full)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %s", path)
+		}
+		defer f.Close()
+		data.Title = opts.Title
+		data.Root = strings.Repeat("../", strings.Count(path, "/"))
+		if err := r.ExecuteTemplate(f, name, data); err != nil {
+			return errors.Wrapf(err, "failed to render %s", path)
+		}
+		return nil
+	}
+
+	if err := write("index"+ext, "index", pageData{Tasks: tasks}); err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err := write(task.Path, "task", pageData{Task: task}); err != nil {
+			return err
+		}
+		for _, day := range task.Days {
+			if err := write(day.Path, "day", pageData{Task: task, Day: day}); err != nil {
+				return err
+			}
+		}
+		for _, item := range task.Items {
+			if err := write(item.Path, "item", pageData{Task: task, Item: item}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newRenderer(format Format) (renderer, string, error) {
+	switch format {
+	case FormatHTML:
+		t, err := htmltemplate.New("").Funcs(htmltemplate.FuncMap(funcs)).ParseFS(templateFS, "templates/*.html")
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to parse html templates")
+		}
+		return t, ".html", nil
+	case FormatMarkdown:
+		t, err := template.New("").Funcs(funcs).ParseFS(templateFS, "templates/*.md")
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to parse markdown templates")
+		}
+		return t, ".md", nil
+	default:
+		return nil, "", errors.Errorf("unsupported site format %q", format)
+	}
+}
+
+var funcs = template.FuncMap{
+	"inc": func(i int) int {
+		return i + 1
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04")
+	},
+}
+
+func buildTaskPage(key string, runs []history.Run, ext string, loc *time.Location) *TaskPage {
+	task := &TaskPage{
+		Key:     key,
+		Task:    runs[0].Task,
+		Variant: runs[0].Variant,
+		Path:    key + "/index" + ext,
+	}
+
+	items := make(map[string]*ItemPage)
+	days := make(map[string]*DayPage)
+	for _, run := range runs {
+		date := run.Time.In(loc).Format("2006-01-02")
+		day, ok := days[date]
+		if !ok {
+			day = &DayPage{Date: date, Path: key + "/" + date + ext}
+			days[date] = day
+			task.Days = append(task.Days, day)
+		}
+
+		view := RunView{Time: run.Time.In(loc)}
+		for _, item := range run.Items {
+			page, ok := items[item.ID]
+			if !ok {
+				page = &ItemPage{
+					ID:   item.ID,
+					Path: key + "/items/" + slug(item.ID) + ext,
+				}
+				items[item.ID] = page
+				task.Items = append(task.Items, page)
+			}
+			// 以最近一次出现的信息为准，缺失的字段保留之前的值
+			if item.Title != "" {
+				page.Title = item.Title
+			}
+			if item.Link != "" {
+				page.Link = item.Link
+			}
+			if item.Summary != "" {
+				page.Summary = item.Summary
+			}
+			if fields := decodeFields(item.Data); fields != nil {
+				page.Fields = fields
+			}
+			page.Appearances = append(page.Appearances, Appearance{
+				Time:    run.Time.In(loc),
+				Date:    date,
+				DayPath: day.Path,
+				Rank:    item.Rank,
+			})
+			if item.Rank > 0 && (page.BestRank == 0 || item.Rank < page.BestRank) {
+				page.BestRank = item.Rank
+			}
+
+			view.Items = append(view.Items, RunItem{
+				Item:     item,
+				Fields:   page.Fields,
+				ItemPath: page.Path,
+			})
+		}
+		day.Runs = append(day.Runs, view)
+	}
+
+	// 最新的日期在前，出现次数多的条目在前
+	sort.Slice(task.Days, func(i, j int) bool {
+		return task.Days[i].Date > task.Days[j].Date
+	})
+	sort.SliceStable(task.Items, func(i, j int) bool {
+		return task.Items[i].Count() > task.Items[j].Count()
+	})
+	return task
+}
+
+func decodeFields(data json.RawMessage) map[string]any {
+	if len(data) == 0 {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// slug 生成条目页面的文件名，保留可读部分并附加哈希避免冲突
+func slug(id string) string {
+	readable := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+	if len(readable) > 40 {
+		readable = readable[:40]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return fmt.Sprintf("%s-%08x", readable, h.Sum32())
+}
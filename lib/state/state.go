@@ -0,0 +1,58 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Store 基于本地 JSON 文件的任务状态存储（已通知的视频、缓存等）
+type Store struct {
+	dir string
+}
+
+// NewStore 创建以 dir 为根目录的状态存储
+func NewStore(dir string) *Store {
+	return &Store{dir: filepath.Join(dir, "state")}
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// Load 读取名为 name 的状态到 v，状态不存在时返回 false 且不修改 v
+func (s *Store) Load(name string, v any) (bool, error) {
+	data, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read state %q", name)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, errors.Wrapf(err, "failed to decode state %q", name)
+	}
+	return true, nil
+}
+
+// Save 保存名为 name 的状态
+func (s *Store) Save(name string, v any) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create state directory")
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode state %q", name)
+	}
+	// 先写临时文件再重命名，避免中途退出留下损坏的状态
+	tmp := s.path(name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write state %q", name)
+	}
+	if err := os.Rename(tmp, s.path(name)); err != nil {
+		return errors.Wrapf(err, "failed to write state %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package workpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// Limiter 按主机划分的令牌桶限速器，可在多个任务间共享
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter 创建每个主机每秒 rate 个请求、最多积累 burst 个令牌的限速器
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Wait 等待 host 的一个令牌，ctx 取消时返回 ctx.Err()
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	for {
+		delay := l.reserve(host)
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 尝试取走一个令牌，令牌不足时返回需要等待的时间
+func (l *Limiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// Run 以最多 workers 个并发对 [0, n) 中的每个 i 执行 fn，返回与条目一一对应的错误，
+// 单个条目失败不影响其他条目；ctx 取消后尚未开始的条目返回 ctx.Err()
+func Run(ctx context.Context, n int, workers int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	var eg errgroup.Group
+	sem := semaphore.NewWeighted(int64(workers))
+	for i := 0; i < n; i++ {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			for j := i; j < n; j++ {
+				errs[j] = err
+			}
+			break
+		}
+		eg.Go(func() error {
+			defer sem.Release(1)
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return nil
+			}
+			errs[i] = fn(ctx, i)
+			return nil
+		})
+	}
+	eg.Wait()
+	return errs
+}
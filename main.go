@@ -3,9 +3,13 @@ package main
 import (
 	"azuserver/config"
 	"azuserver/service"
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 // TODO: test bin options.
@@ -16,11 +20,40 @@ func main() {
 		return
 	}
 
-	task := flag.String("task", "", "oricon_ranking, github_trending, vocaloid_ranking, youtube_user, bilibili_user")
-	userID := flag.String("user-id", "", "User ID for YouTube (@username, UCxxxx, or username) or Bilibili (numeric UID)")
+	task := flag.String("task", "", "oricon_ranking, github_trending, vocaloid_ranking, youtube_user, bilibili_user, youtube_watch, youtube_live, youtube_playlist, bilibili_live, bilibili_dynamic, bilibili_ranking")
+	userID := flag.String("user-id", "", "User ID for YouTube (@username, UCxxxx, or username) or Bilibili (numeric UID); comma separated channels for youtube_watch and youtube_live, or UIDs for bilibili_live and bilibili_dynamic")
 	uid := flag.String("uid", "", "Bilibili user UID (alias for user-id)")
+	language := flag.String("language", "", "Github Trending language, e.g. go")
+	playlist := flag.String("playlist", "", "Comma separated YouTube playlist IDs or URLs for youtube_playlist")
+	sections := flag.String("sections", "", "Comma separated sections: channel tabs listed by youtube_user (videos, shorts, streams; default videos), or per-video statistics added by bilibili_user (comments, danmaku)")
+	boards := flag.String("boards", "", "Comma separated boards for bilibili_ranking: all, anime, music, game, vocaloid, weekly (default all,weekly)")
+	order := flag.String("order", "", "Sort order of the videos listed by bilibili_user: pubdate, click, stow (default pubdate)")
+	since := flag.String("since", "", "Github Trending date range: daily, weekly, monthly")
+	siteDir := flag.String("site", "", "Render stored history into a static archive site in this directory instead of running a task")
+	siteFormat := flag.String("site-format", "html", "Archive site format: html, markdown")
+	output := flag.String("output", "discord", "Where results go: discord, stdout (chunks with lengths), json (typed data), markdown")
+	dryRun := flag.Bool("dry-run", false, "Fetch and render without sending or saving anything (same as -output=stdout)")
 	flag.Parse()
 
+	if *dryRun && *output == "discord" {
+		*output = "stdout"
+	}
+	if err := service.SetOutputMode(*output); err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	if *siteDir != "" {
+		if err := service.GenerateArchiveSite(*siteDir, *siteFormat); err != nil {
+			slog.Error(fmt.Sprintf("Failed to generate archive site: %v", err))
+		}
+		return
+	}
+
+	// Cancel in-flight fetches on Ctrl+C / SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Handle parameterized services
 	if *task == "youtube_user" || *task == "bilibili_user" {
 		params := make(map[string]string)
@@ -32,6 +65,7 @@ func main() {
 				return
 			}
 			params["userID"] = *userID
+			params["sections"] = *sections
 		} else if *task == "bilibili_user" {
 			// Use uid parameter if provided, otherwise fall back to user-id
 			biliUID := *uid
@@ -44,15 +78,50 @@ func main() {
 				return
 			}
 			params["uid"] = biliUID
+			params["order"] = *order
+			params["sections"] = *sections
 		}
 		
-		if err := service.RunServiceWithParams(*task, params); err != nil {
+		if err := service.RunServiceWithParams(ctx, *task, params); err != nil {
+			slog.Error(fmt.Sprintf("Failed to run parameterized service %s: %v", *task, err))
+			return
+		}
+	} else if (*task == "youtube_watch" || *task == "youtube_live") && *userID != "" {
+		if err := service.RunServiceWithParams(ctx, *task, map[string]string{"channels": *userID}); err != nil {
+			slog.Error(fmt.Sprintf("Failed to run parameterized service %s: %v", *task, err))
+			return
+		}
+	} else if (*task == "bilibili_live" || *task == "bilibili_dynamic") && (*uid != "" || *userID != "") {
+		uids := *uid
+		if uids == "" {
+			uids = *userID
+		}
+		if err := service.RunServiceWithParams(ctx, *task, map[string]string{"uids": uids}); err != nil {
+			slog.Error(fmt.Sprintf("Failed to run parameterized service %s: %v", *task, err))
+			return
+		}
+	} else if *task == "bilibili_ranking" && *boards != "" {
+		if err := service.RunServiceWithParams(ctx, *task, map[string]string{"boards": *boards}); err != nil {
+			slog.Error(fmt.Sprintf("Failed to run parameterized service %s: %v", *task, err))
+			return
+		}
+	} else if *task == "youtube_playlist" && *playlist != "" {
+		if err := service.RunServiceWithParams(ctx, *task, map[string]string{"playlists": *playlist}); err != nil {
+			slog.Error(fmt.Sprintf("Failed to run parameterized service %s: %v", *task, err))
+			return
+		}
+	} else if *task == "github_trending" && (*language != "" || *since != "") {
+		params := map[string]string{
+			"language": *language,
+			"since":    *since,
+		}
+		if err := service.RunServiceWithParams(ctx, *task, params); err != nil {
 			slog.Error(fmt.Sprintf("Failed to run parameterized service %s: %v", *task, err))
 			return
 		}
 	} else {
 		// Handle standard services
-		service.RunService(task)
+		service.RunService(ctx, task)
 	}
 
 	slog.Info(fmt.Sprintf("task %s completed", *task))
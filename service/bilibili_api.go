@@ -0,0 +1,284 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/state"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	bilibiliAPIBase     = "https://api.bilibili.com"
+	bilibiliLiveAPIBase = "https://api.live.bilibili.com"
+	bilibiliUserAgent   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+	// bilibiliWbiStateName WBI 密钥和 buvid 的缓存文件名
+	bilibiliWbiStateName = "bilibili_wbi"
+	// bilibiliWbiKeysTTL WBI 密钥每天更换，超过该时间后重新获取
+	bilibiliWbiKeysTTL = 6 * time.Hour
+
+	// bilibiliCodeRiskControl 请求被风控拦截（通常是签名或 Cookie 失效）
+	bilibiliCodeRiskControl = -352
+)
+
+// bilibiliMixinKeyEncTab 由 img_key + sub_key 生成 mixin key 的字符重排表
+var bilibiliMixinKeyEncTab = []int{
+	46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35, 27, 43, 5, 49,
+	33, 9, 42, 19, 29, 28, 14, 39, 12, 38, 41, 13, 37, 48, 7, 16, 24, 55, 40,
+	61, 26, 17, 0, 1, 60, 51, 30, 4, 22, 25, 54, 21, 56, 59, 6, 63, 57, 62, 11,
+	36, 20, 34, 44, 52,
+}
+
+// BilibiliAPIError Bilibili API 返回的非0错误码
+type BilibiliAPIError struct {
+	Path    string
+	Code    int
+	Message string
+}
+
+func (e *BilibiliAPIError) Error() string {
+	return fmt.Sprintf("bilibili API %s returned code %d: %s", e.Path, e.Code, e.Message)
+}
+
+// bilibiliSession WBI 密钥及匿名访问所需的 buvid Cookie
+type bilibiliSession struct {
+	ImgKey    string    `json:"imgKey"`
+	SubKey    string    `json:"subKey"`
+	Buvid3    string    `json:"buvid3"`
+	Buvid4    string    `json:"buvid4"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+var bilibiliSessionCache struct {
+	sync.Mutex
+	loaded  bool
+	session bilibiliSession
+	// loginChecked 本次运行是否已通过 nav 接口检查过登录状态
+	loginChecked bool
+}
+
+// bilibiliMixinKey 按重排表从 img_key + sub_key 中取出前 32 个字符
+func bilibiliMixinKey(imgKey string, subKey string) string {
+	raw := imgKey + subKey
+	var b strings.Builder
+	for _, idx := range bilibiliMixinKeyEncTab {
+		if idx < len(raw) {
+			b.WriteByte(raw[idx])
+		}
+	}
+	key := b.String()
+	if len(key) > 32 {
+		key = key[:32]
+	}
+	return key
+}
+
+// signBilibiliWbi 为请求参数加上 wts 和 w_rid 签名
+func signBilibiliWbi(params url.Values, imgKey string, subKey string, now time.Time) url.Values {
+	signed := url.Values{}
+	for key, values := range params {
+		for _, value := range values {
+			// 签名前去掉值中的 !'()* 字符
+			signed.Add(key, strings.Map(func(r rune) rune {
+				if strings.ContainsRune("!'()*", r) {
+					return -1
+				}
+				return r
+			}, value))
+		}
+	}
+	signed.Set("wts", strconv.FormatInt(now.Unix(), 10))
+
+	// Encode 按键排序，空格需编码为 %20
+	query := strings.ReplaceAll(signed.Encode(), "+", "%20")
+	sum := md5.Sum([]byte(query + bilibiliMixinKey(imgKey, subKey)))
+	signed.Set("w_rid", hex.EncodeToString(sum[:]))
+	return signed
+}
+
+// getBilibiliSession 返回缓存的 WBI 密钥和 buvid，过期或 refresh 为 true 时重新获取
+func getBilibiliSession(ctx context.Context, refresh bool) (bilibiliSession, error) {
+	bilibiliSessionCache.Lock()
+	defer bilibiliSessionCache.Unlock()
+
+	store := state.NewStore(config.GetDataDir())
+	if !bilibiliSessionCache.loaded {
+		bilibiliSessionCache.loaded = true
+		if _, err := store.Load(bilibiliWbiStateName, &bilibiliSessionCache.session); err != nil {
+			slog.Warn("Failed to load Bilibili WBI cache", "error", err)
+		}
+	}
+
+	session := bilibiliSessionCache.session
+	if !refresh && session.ImgKey != "" && time.Since(session.FetchedAt) < bilibiliWbiKeysTTL {
+		// 密钥来自缓存时也要每次运行检查一次登录状态，服务端注销的 Cookie 在多数接口中
+		// 只会返回匿名数据而不是 -101
+		if !bilibiliSessionCache.loginChecked {
+			bilibiliSessionCache.loginChecked = true
+			verifyBilibiliLogin(ctx)
+		}
+		return session, nil
+	}
+
+	fresh, err := fetchBilibiliSession(ctx)
+	if err != nil {
+		return session, err
+	}
+	// fetchBilibiliSession 已检查 nav 的登录状态
+	bilibiliSessionCache.loginChecked = true
+	bilibiliSessionCache.session = fresh
+	if !IsDryRun() {
+		if err := store.Save(bilibiliWbiStateName, fresh); err != nil {
+			slog.Warn("Failed to save Bilibili WBI cache", "error", err)
+		}
+	}
+	return fresh, nil
+}
+
+// fetchBilibiliSession 从 nav 接口获取 WBI 密钥，从 spi 接口获取 buvid
+func fetchBilibiliSession(ctx context.Context) (bilibiliSession, error) {
+	session := bilibiliSession{FetchedAt: time.Now()}
+
+	// 未登录时 nav 返回 -101，但 wbi_img 仍然有效
+	var nav struct {
+		Data struct {
+			IsLogin bool `json:"isLogin"`
+			WbiImg  struct {
+				ImgURL string `json:"img_url"`
+				SubURL string `json:"sub_url"`
+			} `json:"wbi_img"`
+		} `json:"data"`
+	}
+	if err := getBilibiliJSON(ctx, bilibiliAPIBase+"/x/web-interface/nav", nil, "", &nav); err != nil {
+		return session, errors.Wrapf(err, "failed to fetch Bilibili WBI keys")
+	}
+	session.ImgKey = strings.TrimSuffix(path.Base(nav.Data.WbiImg.ImgURL), path.Ext(nav.Data.WbiImg.ImgURL))
+	session.SubKey = strings.TrimSuffix(path.Base(nav.Data.WbiImg.SubURL), path.Ext(nav.Data.WbiImg.SubURL))
+	if session.ImgKey == "" || session.SubKey == "" {
+		return session, errors.New("Bilibili nav response has no WBI keys")
+	}
+	checkBilibiliLogin(nav.Data.IsLogin, "nav reports not logged in")
+
+	var spi struct {
+		Data struct {
+			B3 string `json:"b_3"`
+			B4 string `json:"b_4"`
+		} `json:"data"`
+	}
+	if err := getBilibiliJSON(ctx, bilibiliAPIBase+"/x/frontend/finger/spi", nil, "", &spi); err != nil {
+		// 没有 buvid 时部分接口仍可访问，只记录日志
+		slog.Warn("Failed to fetch Bilibili buvid", "error", err)
+	}
+	session.Buvid3 = spi.Data.B3
+	session.Buvid4 = spi.Data.B4
+	return session, nil
+}
+
+// verifyBilibiliLogin 配置了 SESSDATA 时请求 nav 接口确认仍处于登录状态
+func verifyBilibiliLogin(ctx context.Context) {
+	if findBilibiliCookie(bilibiliCookies(), "SESSDATA") == "" {
+		return
+	}
+	var nav struct {
+		Data struct {
+			IsLogin bool `json:"isLogin"`
+		} `json:"data"`
+	}
+	if err := getBilibiliJSON(ctx, bilibiliAPIBase+"/x/web-interface/nav", nil, "", &nav); err != nil {
+		slog.Warn("Failed to check Bilibili login status", "error", err)
+		return
+	}
+	checkBilibiliLogin(nav.Data.IsLogin, "nav reports not logged in")
+}
+
+// getBilibiliJSON 发送带登录 Cookie 的 GET 请求并将响应解码到 v，不检查业务错误码
+func getBilibiliJSON(ctx context.Context, apiURL string, session *bilibiliSession, referer string, v any) error {
+	if err := waitForURL(ctx, apiURL); err != nil {
+		return err
+	}
+	req := resty.New().R().
+		SetContext(ctx).
+		SetHeader("User-Agent", bilibiliUserAgent).
+		SetHeader("Accept", "application/json, text/plain, */*")
+	if referer != "" {
+		req.SetHeader("Referer", referer)
+	}
+	if cookie := bilibiliCookieHeader(session); cookie != "" {
+		req.SetHeader("Cookie", cookie)
+	}
+
+	resp, err := req.Get(apiURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to request %s", apiURL)
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("error status code %d for %s", resp.StatusCode(), apiURL)
+	}
+	if err := json.Unmarshal(resp.Body(), v); err != nil {
+		return errors.Wrapf(err, "failed to decode response from %s", apiURL)
+	}
+	return nil
+}
+
+// callBilibiliAPI 请求 api.bilibili.com 的接口，signed 为 true 时附加 WBI 签名，成功时将 data 解码到 v；
+// 被风控拦截时刷新密钥后重试一次
+func callBilibiliAPI(ctx context.Context, apiPath string, params url.Values, signed bool, referer string, v any) error {
+	return callBilibiliAPIAt(ctx, bilibiliAPIBase, apiPath, params, signed, referer, v)
+}
+
+// callBilibiliAPIAt 与 callBilibiliAPI 相同，但请求 baseURL 下的接口（如直播接口 api.live.bilibili.com）
+func callBilibiliAPIAt(ctx context.Context, baseURL string, apiPath string, params url.Values, signed bool, referer string, v any) error {
+	refresh := false
+	for attempt := 0; ; attempt++ {
+		session, err := getBilibiliSession(ctx, refresh)
+		if err != nil && session.ImgKey == "" && signed {
+			return err
+		}
+
+		query := params
+		if signed {
+			query = signBilibiliWbi(params, session.ImgKey, session.SubKey, time.Now())
+		}
+		apiURL := baseURL + apiPath
+		if len(query) > 0 {
+			apiURL += "?" + query.Encode()
+		}
+
+		var resp struct {
+			Code    int             `json:"code"`
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+		}
+		if err := getBilibiliJSON(ctx, apiURL, &session, referer, &resp); err != nil {
+			return err
+		}
+		if resp.Code == bilibiliCodeRiskControl && attempt == 0 {
+			slog.Warn("Bilibili API rejected by risk control, refreshing WBI keys", "path", apiPath)
+			refresh = true
+			continue
+		}
+		if resp.Code == bilibiliCodeNotLoggedIn {
+			checkBilibiliLogin(false, apiPath+" requires login")
+		}
+		if resp.Code != 0 {
+			return &BilibiliAPIError{Path: apiPath, Code: resp.Code, Message: resp.Message}
+		}
+		if v == nil || len(resp.Data) == 0 {
+			return nil
+		}
+		return errors.Wrapf(json.Unmarshal(resp.Data, v), "failed to decode data from %s", apiPath)
+	}
+}
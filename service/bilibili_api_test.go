@@ -0,0 +1,75 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// 公开文档中的 WBI 签名示例
+const (
+	testBilibiliImgKey = "7cd084941338484aae1ad9425b84077c"
+	testBilibiliSubKey = "4932caff0ff746eab6f01bf08b70ac45"
+)
+
+func TestBilibiliMixinKey(t *testing.T) {
+	tests := []struct {
+		imgKey, subKey string
+		want           string
+	}{
+		{testBilibiliImgKey, testBilibiliSubKey, "ea1db124af3c7062474693fa704f4ff8"},
+		// 密钥过短时跳过越界的位置
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := bilibiliMixinKey(tt.imgKey, tt.subKey); got != tt.want {
+			t.Errorf("bilibiliMixinKey(%q, %q) = %q, want %q", tt.imgKey, tt.subKey, got, tt.want)
+		}
+	}
+}
+
+func TestSignBilibiliWbi(t *testing.T) {
+	now := time.Unix(1702204169, 0)
+	tests := []struct {
+		name   string
+		params url.Values
+		want   url.Values
+	}{
+		{
+			name:   "documented example",
+			params: url.Values{"foo": {"114"}, "bar": {"514"}, "zab": {"1919810"}},
+			want: url.Values{
+				"foo": {"114"}, "bar": {"514"}, "zab": {"1919810"},
+				"wts": {"1702204169"}, "w_rid": {"8f6f2b5b3d485fe1886cec6a0be8c5d4"},
+			},
+		},
+		{
+			name:   "strips !'()* from values",
+			params: url.Values{"keyword": {"a!b'(c)*"}},
+			want:   url.Values{"keyword": {"abc"}, "wts": {"1702204169"}},
+		},
+		{
+			name:   "no params",
+			params: nil,
+			want:   url.Values{"wts": {"1702204169"}},
+		},
+	}
+	for _, tt := range tests {
+		signed := signBilibiliWbi(tt.params, testBilibiliImgKey, testBilibiliSubKey, now)
+		for key, values := range tt.want {
+			if got := signed.Get(key); got != values[0] {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got, values[0])
+			}
+		}
+		if len(signed.Get("w_rid")) != 32 {
+			t.Errorf("%s: w_rid = %q, want 32 hex characters", tt.name, signed.Get("w_rid"))
+		}
+	}
+
+	// 签名不修改传入的参数
+	params := url.Values{"mid": {"1"}}
+	signBilibiliWbi(params, testBilibiliImgKey, testBilibiliSubKey, now)
+	if len(params) != 1 {
+		t.Errorf("signBilibiliWbi modified its input: %v", params)
+	}
+}
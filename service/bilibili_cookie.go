@@ -0,0 +1,223 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/state"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/pkg/errors"
+)
+
+// bilibiliCookieStateName 已通知过失效的登录 Cookie 的状态文件名
+const bilibiliCookieStateName = "bilibili_cookie"
+
+// bilibiliCodeNotLoggedIn 接口要求登录但请求未登录（Cookie 缺失或已失效）
+const bilibiliCodeNotLoggedIn = -101
+
+var bilibiliCookieJar struct {
+	once    sync.Once
+	cookies []*http.Cookie
+}
+
+var bilibiliCookieReport struct {
+	sync.Mutex
+	reported bool
+}
+
+// bilibiliCookieState 上次通知失效的 SESSDATA 指纹，同一个 Cookie 只通知一次
+type bilibiliCookieState struct {
+	ExpiredFingerprint string    `json:"expiredFingerprint"`
+	ReportedAt         time.Time `json:"reportedAt"`
+}
+
+// bilibiliCookies 返回配置的登录 Cookie，第一次调用时读取，SESSDATA 已过期时通知系统 webhook
+func bilibiliCookies() []*http.Cookie {
+	bilibiliCookieJar.once.Do(func() {
+		cookies, err := loadBilibiliCookies(config.GetBilibiliCookie())
+		if err != nil {
+			slog.Warn("Failed to load Bilibili cookies", "error", err)
+		}
+		bilibiliCookieJar.cookies = cookies
+
+		sessdata := findBilibiliCookie(cookies, "SESSDATA")
+		if expiry, ok := bilibiliSESSDATAExpiry(sessdata); ok && time.Now().After(expiry) {
+			reportBilibiliCookieExpired(sessdata, fmt.Sprintf("SESSDATA expired at %s", expiry.In(config.GetLocation()).Format("2006-01-02 15:04 MST")))
+		}
+	})
+	return bilibiliCookieJar.cookies
+}
+
+// loadBilibiliCookies 读取 Cookie 文件，再用单独配置的值覆盖同名 Cookie
+func loadBilibiliCookies(cfg config.BilibiliCookieConfig) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	var err error
+	if cfg.File != "" {
+		data, readErr := os.ReadFile(cfg.File)
+		if readErr != nil {
+			err = errors.Wrapf(readErr, "failed to read Bilibili cookie file %s", cfg.File)
+		} else {
+			cookies = parseBilibiliCookies(string(data))
+		}
+	}
+	if cfg.SESSDATA != "" {
+		cookies = setBilibiliCookie(cookies, "SESSDATA", cfg.SESSDATA)
+	}
+	if cfg.BiliJct != "" {
+		cookies = setBilibiliCookie(cookies, "bili_jct", cfg.BiliJct)
+	}
+	if cfg.Buvid3 != "" {
+		cookies = setBilibiliCookie(cookies, "buvid3", cfg.Buvid3)
+	}
+	return cookies, err
+}
+
+// parseBilibiliCookies 解析 Netscape cookies.txt（只保留 bilibili.com 的 Cookie）或 "name=value; ..." 格式的 Cookie
+func parseBilibiliCookies(data string) []*http.Cookie {
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// curl 等工具导出的 HttpOnly Cookie 以 #HttpOnly_ 开头
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// cookies.txt: domain, includeSubdomains, path, secure, expires, name, value
+		if fields := strings.Split(line, "\t"); len(fields) == 7 {
+			if strings.HasSuffix(fields[0], "bilibili.com") {
+				cookies = setBilibiliCookie(cookies, fields[5], fields[6])
+			}
+			continue
+		}
+		for _, pair := range strings.Split(line, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && name != "" {
+				cookies = setBilibiliCookie(cookies, name, value)
+			}
+		}
+	}
+	return cookies
+}
+
+// setBilibiliCookie 设置 Cookie，已有同名 Cookie 时替换其值
+func setBilibiliCookie(cookies []*http.Cookie, name string, value string) []*http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			cookie.Value = value
+			return cookies
+		}
+	}
+	return append(cookies, &http.Cookie{Name: name, Value: value})
+}
+
+func findBilibiliCookie(cookies []*http.Cookie, name string) string {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// bilibiliSESSDATAExpiry 解析 SESSDATA 中的过期时间，SESSDATA 形如 "xxxx%2C1700000000%2Cxxxx"
+func bilibiliSESSDATAExpiry(sessdata string) (time.Time, bool) {
+	if unescaped, err := url.QueryUnescape(sessdata); err == nil {
+		sessdata = unescaped
+	}
+	fields := strings.Split(sessdata, ",")
+	if len(fields) < 2 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// bilibiliCookieHeader 返回请求使用的 Cookie 请求头，未配置 buvid 时使用 session 中匿名获取的 buvid
+func bilibiliCookieHeader(session *bilibiliSession) string {
+	cookies := bilibiliCookies()
+	parts := make([]string, 0, len(cookies)+2)
+	for _, cookie := range cookies {
+		parts = append(parts, cookie.Name+"="+cookie.Value)
+	}
+	if session != nil {
+		if session.Buvid3 != "" && findBilibiliCookie(cookies, "buvid3") == "" {
+			parts = append(parts, "buvid3="+session.Buvid3)
+		}
+		if session.Buvid4 != "" && findBilibiliCookie(cookies, "buvid4") == "" {
+			parts = append(parts, "buvid4="+session.Buvid4)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// checkBilibiliLogin 配置了 SESSDATA 但接口报告未登录时通知 Cookie 失效
+func checkBilibiliLogin(loggedIn bool, reason string) {
+	sessdata := findBilibiliCookie(bilibiliCookies(), "SESSDATA")
+	if sessdata == "" || loggedIn {
+		return
+	}
+	reportBilibiliCookieExpired(sessdata, reason)
+}
+
+// reportBilibiliCookieExpired 向系统 webhook 通知登录 Cookie 失效，每次运行最多通知一次，
+// 同一个 SESSDATA 只通知一次，更换 Cookie 后重新计算
+func reportBilibiliCookieExpired(sessdata string, reason string) {
+	bilibiliCookieReport.Lock()
+	defer bilibiliCookieReport.Unlock()
+	if bilibiliCookieReport.reported {
+		return
+	}
+	bilibiliCookieReport.reported = true
+
+	sum := sha256.Sum256([]byte(sessdata))
+	fingerprint := hex.EncodeToString(sum[:8])
+
+	store := state.NewStore(config.GetDataDir())
+	var reported bilibiliCookieState
+	if _, err := store.Load(bilibiliCookieStateName, &reported); err != nil {
+		slog.Warn("Failed to load Bilibili cookie state", "error", err)
+	}
+	if reported.ExpiredFingerprint == fingerprint {
+		slog.Warn("Bilibili login cookie is still expired", "reason", reason)
+		return
+	}
+
+	notifySystem(fmt.Sprintf("⚠️ Bilibili 登录 Cookie 已失效（%s），需要登录的数据将以未登录状态获取，请更新 `bilibili_cookie` 配置", reason))
+	if !IsDryRun() {
+		reported = bilibiliCookieState{ExpiredFingerprint: fingerprint, ReportedAt: time.Now()}
+		if err := store.Save(bilibiliCookieStateName, reported); err != nil {
+			slog.Warn("Failed to save Bilibili cookie state", "error", err)
+		}
+	}
+}
+
+// newBilibiliCollector 创建带有统一 User-Agent 和登录 Cookie、请求绑定到 ctx 的 colly 采集器
+func newBilibiliCollector(ctx context.Context) *colly.Collector {
+	c := colly.NewCollector(
+		colly.UserAgent(bilibiliUserAgent),
+	)
+	bindCollector(ctx, c)
+	c.OnRequest(func(r *colly.Request) {
+		if cookie := bilibiliCookieHeader(nil); cookie != "" {
+			r.Headers.Set("Cookie", cookie)
+		}
+	})
+	return c
+}
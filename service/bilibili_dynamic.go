@@ -0,0 +1,353 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/state"
+	"azuserver/lib/workpool"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const ServiceNameBilibiliDynamic = "Bilibili Dynamic"
+
+// bilibiliDynamicStateName 各用户最后看到的动态ID的状态文件名
+const bilibiliDynamicStateName = "bilibili_dynamic"
+
+// BilibiliDynamicType 动态的类型
+type BilibiliDynamicType string
+
+const (
+	BilibiliDynamicTypeVideo   BilibiliDynamicType = "video"   // 投稿视频
+	BilibiliDynamicTypeDraw    BilibiliDynamicType = "draw"    // 图文
+	BilibiliDynamicTypeText    BilibiliDynamicType = "text"    // 纯文字
+	BilibiliDynamicTypeForward BilibiliDynamicType = "forward" // 转发
+	BilibiliDynamicTypeArticle BilibiliDynamicType = "article" // 专栏
+	BilibiliDynamicTypeOther   BilibiliDynamicType = "other"
+)
+
+// bilibiliDynamicTypes 接口中的动态类型 → BilibiliDynamicType
+var bilibiliDynamicTypes = map[string]BilibiliDynamicType{
+	"DYNAMIC_TYPE_AV":      BilibiliDynamicTypeVideo,
+	"DYNAMIC_TYPE_DRAW":    BilibiliDynamicTypeDraw,
+	"DYNAMIC_TYPE_WORD":    BilibiliDynamicTypeText,
+	"DYNAMIC_TYPE_FORWARD": BilibiliDynamicTypeForward,
+	"DYNAMIC_TYPE_ARTICLE": BilibiliDynamicTypeArticle,
+}
+
+// BilibiliDynamic 一条动态，转发动态的原动态在 Orig 中
+type BilibiliDynamic struct {
+	ID          string              `json:"id"`
+	Type        BilibiliDynamicType `json:"type"`
+	RawType     string              `json:"rawType"`
+	Author      string              `json:"author"`
+	AuthorUID   string              `json:"authorUid"`
+	PublishedAt time.Time           `json:"publishedAt"`
+	Pinned      bool                `json:"pinned"`
+	Text        string              `json:"text"`
+	Title       string              `json:"title"`
+	Images      []string            `json:"images"`
+	// 视频、专栏等的链接，没有时为空
+	LinkURL string           `json:"linkUrl"`
+	URL     string           `json:"url"`
+	Orig    *BilibiliDynamic `json:"orig,omitempty"`
+}
+
+// BilibiliDynamicReport 某个用户的新动态
+type BilibiliDynamicReport struct {
+	UID      string            `json:"uid"`
+	Username string            `json:"username"`
+	SpaceURL string            `json:"spaceUrl"`
+	Dynamics []BilibiliDynamic `json:"dynamics"`
+}
+
+// bilibiliDynamicStateEntry 用户最后看到的动态
+type bilibiliDynamicStateEntry struct {
+	LastID    string    `json:"lastId"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// bilibiliDynamicState UID → 最后看到的动态
+type bilibiliDynamicState map[string]bilibiliDynamicStateEntry
+
+// bilibiliDynamicItem feed/space 接口返回的单条动态
+type bilibiliDynamicItem struct {
+	IDStr   string `json:"id_str"`
+	Type    string `json:"type"`
+	Modules struct {
+		ModuleAuthor struct {
+			Mid   int64  `json:"mid"`
+			Name  string `json:"name"`
+			PubTS int64  `json:"pub_ts"`
+		} `json:"module_author"`
+		ModuleDynamic struct {
+			Desc *struct {
+				Text string `json:"text"`
+			} `json:"desc"`
+			Major *bilibiliDynamicMajor `json:"major"`
+		} `json:"module_dynamic"`
+		ModuleTag *struct {
+			Text string `json:"text"`
+		} `json:"module_tag"`
+	} `json:"modules"`
+	Orig *bilibiliDynamicItem `json:"orig"`
+}
+
+// bilibiliDynamicMajor 动态的主体内容，Type 决定哪个字段有值
+type bilibiliDynamicMajor struct {
+	Type    string `json:"type"`
+	Archive *struct {
+		Bvid    string `json:"bvid"`
+		Title   string `json:"title"`
+		Desc    string `json:"desc"`
+		Cover   string `json:"cover"`
+		JumpURL string `json:"jump_url"`
+	} `json:"archive"`
+	Draw *struct {
+		Items []struct {
+			Src string `json:"src"`
+		} `json:"items"`
+	} `json:"draw"`
+	Article *struct {
+		ID      int64    `json:"id"`
+		Title   string   `json:"title"`
+		Desc    string   `json:"desc"`
+		Covers  []string `json:"covers"`
+		JumpURL string   `json:"jump_url"`
+	} `json:"article"`
+	// 新版图文和专栏以 opus 形式返回
+	Opus *struct {
+		Title   string `json:"title"`
+		Summary struct {
+			Text string `json:"text"`
+		} `json:"summary"`
+		Pics []struct {
+			URL string `json:"url"`
+		} `json:"pics"`
+		JumpURL string `json:"jump_url"`
+	} `json:"opus"`
+}
+
+// bilibiliURL 补全接口中以 // 开头的链接
+func bilibiliURL(rawURL string) string {
+	if strings.HasPrefix(rawURL, "//") {
+		return "https:" + rawURL
+	}
+	return rawURL
+}
+
+// toDynamic 转换为 BilibiliDynamic
+func (item *bilibiliDynamicItem) toDynamic() BilibiliDynamic {
+	author := item.Modules.ModuleAuthor
+	dynamic := BilibiliDynamic{
+		ID:        item.IDStr,
+		Type:      bilibiliDynamicTypes[item.Type],
+		RawType:   item.Type,
+		Author:    author.Name,
+		AuthorUID: strconv.FormatInt(author.Mid, 10),
+		Pinned:    item.Modules.ModuleTag != nil && item.Modules.ModuleTag.Text == "置顶",
+		URL:       fmt.Sprintf("https://t.bilibili.com/%s", item.IDStr),
+	}
+	if dynamic.Type == "" {
+		dynamic.Type = BilibiliDynamicTypeOther
+	}
+	if author.PubTS > 0 {
+		dynamic.PublishedAt = time.Unix(author.PubTS, 0)
+	}
+	if desc := item.Modules.ModuleDynamic.Desc; desc != nil {
+		dynamic.Text = desc.Text
+	}
+
+	if major := item.Modules.ModuleDynamic.Major; major != nil {
+		switch {
+		case major.Archive != nil:
+			dynamic.Title = major.Archive.Title
+			dynamic.LinkURL = bilibiliURL(major.Archive.JumpURL)
+			if dynamic.LinkURL == "" && major.Archive.Bvid != "" {
+				dynamic.LinkURL = fmt.Sprintf("https://www.bilibili.com/video/%s", major.Archive.Bvid)
+			}
+			if major.Archive.Cover != "" {
+				dynamic.Images = append(dynamic.Images, bilibiliURL(major.Archive.Cover))
+			}
+		case major.Draw != nil:
+			for _, image := range major.Draw.Items {
+				dynamic.Images = append(dynamic.Images, bilibiliURL(image.Src))
+			}
+		case major.Article != nil:
+			dynamic.Title = major.Article.Title
+			dynamic.LinkURL = bilibiliURL(major.Article.JumpURL)
+			if dynamic.Text == "" {
+				dynamic.Text = major.Article.Desc
+			}
+			for _, cover := range major.Article.Covers {
+				dynamic.Images = append(dynamic.Images, bilibiliURL(cover))
+			}
+		case major.Opus != nil:
+			dynamic.Title = major.Opus.Title
+			if dynamic.Text == "" {
+				dynamic.Text = major.Opus.Summary.Text
+			}
+			if dynamic.Type == BilibiliDynamicTypeArticle {
+				dynamic.LinkURL = bilibiliURL(major.Opus.JumpURL)
+			}
+			for _, pic := range major.Opus.Pics {
+				dynamic.Images = append(dynamic.Images, bilibiliURL(pic.URL))
+			}
+		}
+	}
+
+	if item.Orig != nil {
+		orig := item.Orig.toDynamic()
+		dynamic.Orig = &orig
+	}
+	return dynamic
+}
+
+// newerBilibiliDynamicID 判断动态ID a 是否比 b 新，动态ID随发布时间递增
+func newerBilibiliDynamicID(a string, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA != nil || errB != nil {
+		return len(a) > len(b) || (len(a) == len(b) && a > b)
+	}
+	return x > y
+}
+
+// GetBilibiliUserDynamics 获取用户动态列表的第一页，最新的在前（置顶动态除外）
+func GetBilibiliUserDynamics(ctx context.Context, uid string) ([]BilibiliDynamic, error) {
+	params := url.Values{
+		"host_mid": {uid},
+		"offset":   {""},
+		"features": {"itemOpusStyle"},
+	}
+	var data struct {
+		Items []bilibiliDynamicItem `json:"items"`
+	}
+	referer := fmt.Sprintf("https://space.bilibili.com/%s/dynamic", uid)
+	if err := callBilibiliAPI(ctx, "/x/polymer/web-dynamic/v1/feed/space", params, true, referer, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to get Bilibili dynamics for uid %s", uid)
+	}
+
+	dynamics := make([]BilibiliDynamic, 0, len(data.Items))
+	for i := range data.Items {
+		if data.Items[i].IDStr == "" {
+			continue
+		}
+		dynamics = append(dynamics, data.Items[i].toDynamic())
+	}
+	return dynamics, nil
+}
+
+// SendBilibiliDynamic 检查用户的动态，发送上次运行后发布的新动态
+func SendBilibiliDynamic(ctx context.Context, uids []string) error {
+	if len(uids) == 0 {
+		return errors.New("no Bilibili users to watch for dynamics")
+	}
+
+	store := state.NewStore(config.GetDataDir())
+	seen := bilibiliDynamicState{}
+	if _, err := store.Load(bilibiliDynamicStateName, &seen); err != nil {
+		return err
+	}
+
+	// 并发获取各用户的动态，之后按顺序比对和发送
+	fetched := make([][]BilibiliDynamic, len(uids))
+	errs := workpool.Run(ctx, len(uids), fetchWorkers, func(ctx context.Context, i int) error {
+		var err error
+		fetched[i], err = GetBilibiliUserDynamics(ctx, uids[i])
+		return err
+	})
+
+	var failed int
+	for i, uid := range uids {
+		err := errs[i]
+		if err == nil {
+			err = checkBilibiliUserDynamics(uid, fetched[i], seen)
+		}
+		if err != nil {
+			failed++
+			slog.Warn("Failed to check Bilibili dynamics", "uid", uid, "error", err)
+		}
+	}
+
+	if !IsDryRun() {
+		if err := store.Save(bilibiliDynamicStateName, seen); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to check %d of %d Bilibili users", failed, len(uids))
+	}
+	return nil
+}
+
+func checkBilibiliUserDynamics(uid string, dynamics []BilibiliDynamic, seen bilibiliDynamicState) error {
+	previous, watched := seen[uid]
+	// 之前没有取到任何动态（新账号或被风控返回空列表）时仍按第一次监视处理，
+	// 否则空的 LastID 会让整个列表都被当作新动态
+	watched = watched && previous.LastID != ""
+	current := bilibiliDynamicStateEntry{LastID: previous.LastID, CheckedAt: time.Now()}
+
+	// 置顶动态可能很旧，按ID而不是位置判断是否为新动态
+	var newDynamics []BilibiliDynamic
+	for _, dynamic := range dynamics {
+		if newerBilibiliDynamicID(dynamic.ID, current.LastID) {
+			current.LastID = dynamic.ID
+		}
+		if watched && newerBilibiliDynamicID(dynamic.ID, previous.LastID) {
+			newDynamics = append(newDynamics, dynamic)
+		}
+	}
+
+	// 第一次监视的用户只记录最新的动态，避免一次性推送整个列表
+	if !watched {
+		seen[uid] = current
+		slog.Info("Started watching Bilibili dynamics", "uid", uid, "lastID", current.LastID)
+		return nil
+	}
+	if len(newDynamics) == 0 {
+		seen[uid] = current
+		return nil
+	}
+
+	// 按发布顺序通知
+	sort.Slice(newDynamics, func(i, j int) bool {
+		return newerBilibiliDynamicID(newDynamics[j].ID, newDynamics[i].ID)
+	})
+	report := &BilibiliDynamicReport{
+		UID:      uid,
+		Username: newDynamics[0].Author,
+		SpaceURL: fmt.Sprintf("https://space.bilibili.com/%s/dynamic", uid),
+		Dynamics: newDynamics,
+	}
+
+	items := make([]history.Item, 0, len(newDynamics))
+	for idx, dynamic := range newDynamics {
+		title := dynamic.Title
+		if title == "" {
+			title = dynamic.Text
+		}
+		items = append(items, newHistoryItem(dynamic.ID, idx+1, title, dynamic.URL, dynamic.Author, dynamic))
+	}
+	if err := publish(publication{
+		Task:     AzutvTaskTypeBilibiliDynamic,
+		Variant:  uid,
+		Username: ServiceNameBilibiliDynamic,
+		Items:    items,
+		Data:     report,
+	}); err != nil {
+		return err
+	}
+
+	// 发送成功后才更新最后看到的动态，失败的动态下次运行时重试
+	seen[uid] = current
+	return nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewerBilibiliDynamicID(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"900000000000000002", "900000000000000001", true},
+		{"900000000000000001", "900000000000000002", false},
+		{"900000000000000001", "900000000000000001", false},
+		{"1000", "999", true},
+		{"999", "1000", false},
+		{"1", "", true},
+		{"", "1", false},
+		// 超出 uint64 时按长度和字典序比较
+		{"99999999999999999999", "9999999999999999999", true},
+		{"99999999999999999998", "99999999999999999999", false},
+	}
+	for _, tt := range tests {
+		if got := newerBilibiliDynamicID(tt.a, tt.b); got != tt.want {
+			t.Errorf("newerBilibiliDynamicID(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBilibiliUserDynamicsFirstRun(t *testing.T) {
+	// 试运行时 publish 将消息打印到标准输出，用它判断是否发送了动态
+	if err := SetOutputMode(string(OutputModeStdout)); err != nil {
+		t.Fatal(err)
+	}
+	defer SetOutputMode(string(OutputModeDiscord))
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	dynamics := []BilibiliDynamic{{ID: "3", Text: "c"}, {ID: "2", Text: "b"}, {ID: "1", Text: "a"}}
+	tests := []struct {
+		name        string
+		seen        bilibiliDynamicState
+		wantPublish bool
+	}{
+		{"not watched", bilibiliDynamicState{}, false},
+		// 上次取到空列表时只记录了空的 LastID，不能把整个列表当作新动态
+		{"empty last ID", bilibiliDynamicState{"42": {}}, false},
+		{"watched", bilibiliDynamicState{"42": {LastID: "2"}}, true},
+	}
+	for _, tt := range tests {
+		if err := out.Truncate(0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := out.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkBilibiliUserDynamics("42", dynamics, tt.seen); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := tt.seen["42"].LastID; got != "3" {
+			t.Errorf("%s: LastID = %q, want %q", tt.name, got, "3")
+		}
+		info, err := out.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if published := info.Size() > 0; published != tt.wantPublish {
+			t.Errorf("%s: published = %v, want %v", tt.name, published, tt.wantPublish)
+		}
+	}
+}
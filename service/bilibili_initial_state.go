@@ -0,0 +1,106 @@
+package service
+
+import (
+	"azuserver/config"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// bilibiliInitialStateMarkers __INITIAL_STATE__ 在页面脚本中的几种赋值写法
+var bilibiliInitialStateMarkers = []string{
+	"window.__INITIAL_STATE__=",
+	"window.__INITIAL_STATE__ = ",
+	"__INITIAL_STATE__=",
+}
+
+// extractBilibiliInitialState 从页面脚本中截取 __INITIAL_STATE__ 对象并解码到 v，脚本中没有该对象时返回 false
+func extractBilibiliInitialState(script string, v any) (bool, error) {
+	found, err := extractScriptJSON(script, bilibiliInitialStateMarkers, v)
+	return found, errors.Wrapf(err, "failed to decode __INITIAL_STATE__")
+}
+
+// biliVideoPage 视频的一个分P
+type biliVideoPage struct {
+	Cid      int64  `json:"cid"`
+	Page     int    `json:"page"`
+	Part     string `json:"part"`
+	Duration int    `json:"duration"`
+}
+
+// biliVideoData 视频页面 __INITIAL_STATE__ 中的 videoData，只包含当前视频本身的信息
+type biliVideoData struct {
+	Bvid     string `json:"bvid"`
+	Aid      int64  `json:"aid"`
+	Videos   int    `json:"videos"` // 分P数
+	Title    string `json:"title"`
+	Desc     string `json:"desc"`
+	Pic      string `json:"pic"`
+	Pubdate  int64  `json:"pubdate"`
+	Duration int    `json:"duration"`
+	Owner    struct {
+		Mid  int64  `json:"mid"`
+		Name string `json:"name"`
+		Face string `json:"face"`
+	} `json:"owner"`
+	Stat struct {
+		View     int64 `json:"view"`
+		Danmaku  int64 `json:"danmaku"`
+		Reply    int64 `json:"reply"`
+		Favorite int64 `json:"favorite"`
+		Coin     int64 `json:"coin"`
+		Share    int64 `json:"share"`
+		Like     int64 `json:"like"`
+	} `json:"stat"`
+	Pages []biliVideoPage `json:"pages"`
+}
+
+// biliVideoInitialState 视频页面的 __INITIAL_STATE__
+type biliVideoInitialState struct {
+	Bvid      string        `json:"bvid"`
+	VideoData biliVideoData `json:"videoData"`
+}
+
+// toVideoInfo 转换为 BilibiliVideoInfo，分P多于一个时列出各分P
+func (d *biliVideoData) toVideoInfo() *BilibiliVideoInfo {
+	video := &BilibiliVideoInfo{
+		BvID:          d.Bvid,
+		AvID:          strconv.FormatInt(d.Aid, 10),
+		Title:         d.Title,
+		ViewCount:     d.Stat.View,
+		LikeCount:     d.Stat.Like,
+		CoinCount:     d.Stat.Coin,
+		FavoriteCount: d.Stat.Favorite,
+		ShareCount:    d.Stat.Share,
+		ReplyCount:    d.Stat.Reply,
+		Description:   d.Desc,
+		CoverURL:      bilibiliURL(d.Pic),
+		VideoURL:      fmt.Sprintf("https://www.bilibili.com/video/%s", d.Bvid),
+		Author:        d.Owner.Name,
+		DanmakuCount:  d.Stat.Danmaku,
+	}
+	if len(d.Pages) > 0 {
+		video.CID = d.Pages[0].Cid
+		video.cidSeconds = d.Pages[0].Duration
+	}
+	if d.Duration > 0 {
+		video.Duration = formatDuration(d.Duration)
+	}
+	if d.Pubdate > 0 {
+		video.PublishedAt = time.Unix(d.Pubdate, 0)
+		video.UploadDate = video.PublishedAt.In(config.GetLocation()).Format("2006-01-02 15:04:05")
+	}
+	if len(d.Pages) > 1 {
+		for _, page := range d.Pages {
+			video.Parts = append(video.Parts, BilibiliVideoPart{
+				Page:     page.Page,
+				Title:    page.Part,
+				Duration: formatDuration(page.Duration),
+				URL:      fmt.Sprintf("%s?p=%d", video.VideoURL, page.Page),
+			})
+		}
+	}
+	return video
+}
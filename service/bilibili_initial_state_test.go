@@ -0,0 +1,37 @@
+package service
+
+import "testing"
+
+func TestBilibiliVideoInitialState(t *testing.T) {
+	// 相关推荐和 UP 主卡片中的数值不能被当作当前视频的数据
+	const script = `window.__INITIAL_STATE__={"bvid":"BV1xx411c7mD","videoData":{"bvid":"BV1xx411c7mD","aid":170001,"videos":2,"title":"曲","desc":"简介","pic":"//i0.hdslb.com/a.jpg","pubdate":1700000000,"duration":300,` +
+		`"owner":{"mid":1,"name":"P主"},"stat":{"view":1000,"danmaku":50,"reply":20,"favorite":30,"coin":40,"share":5,"like":60},` +
+		`"pages":[{"cid":111,"page":1,"part":"本篇","duration":240},{"cid":222,"page":2,"part":"彩蛋","duration":60}]},` +
+		`"related":[{"bvid":"BV1other","stat":{"view":999999,"like":888888}}],"upData":{"fans":123456}};(function(){})();`
+
+	var state biliVideoInitialState
+	found, err := extractBilibiliInitialState(script, &state)
+	if !found || err != nil {
+		t.Fatalf("extractBilibiliInitialState: found = %v, err = %v", found, err)
+	}
+	video := state.VideoData.toVideoInfo()
+
+	if video.BvID != "BV1xx411c7mD" || video.AvID != "170001" || video.Author != "P主" || video.CoverURL != "https://i0.hdslb.com/a.jpg" {
+		t.Errorf("unexpected video: %+v", video)
+	}
+	if video.ViewCount != 1000 || video.LikeCount != 60 || video.CoinCount != 40 || video.FavoriteCount != 30 ||
+		video.ShareCount != 5 || video.ReplyCount != 20 {
+		t.Errorf("unexpected stats: %+v", video)
+	}
+	if video.Duration != "5:00" {
+		t.Errorf("Duration = %q, want 5:00", video.Duration)
+	}
+	if len(video.Parts) != 2 || video.Parts[1].Title != "彩蛋" || video.Parts[1].URL != "https://www.bilibili.com/video/BV1xx411c7mD?p=2" {
+		t.Errorf("unexpected parts: %+v", video.Parts)
+	}
+
+	found, _ = extractBilibiliInitialState(`window.__playinfo__={}`, &state)
+	if found {
+		t.Error("found __INITIAL_STATE__ in a script without it")
+	}
+}
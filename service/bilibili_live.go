@@ -0,0 +1,232 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/state"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const ServiceNameBilibiliLive = "Bilibili Live"
+
+// bilibiliLiveStateName 直播间状态的文件名
+const bilibiliLiveStateName = "bilibili_live"
+
+// bilibiliLiveStatusLive 直播间的 live_status：0 未开播，1 直播中，2 轮播（视为未开播）
+const bilibiliLiveStatusLive = 1
+
+// BilibiliLiveEvent 直播间状态变化
+type BilibiliLiveEvent string
+
+const (
+	BilibiliLiveEventStart BilibiliLiveEvent = "live"
+	BilibiliLiveEventEnd   BilibiliLiveEvent = "ended"
+)
+
+// BilibiliLiveRoom 用户的直播间
+type BilibiliLiveRoom struct {
+	UID        string    `json:"uid"`
+	RoomID     int64     `json:"roomId"`
+	Username   string    `json:"username"`
+	Title      string    `json:"title"`
+	Area       string    `json:"area"`
+	ParentArea string    `json:"parentArea"`
+	Online     int64     `json:"online"`
+	Live       bool      `json:"live"`
+	LiveSince  time.Time `json:"liveSince"`
+	CoverURL   string    `json:"coverUrl"`
+	RoomURL    string    `json:"roomUrl"`
+}
+
+// BilibiliLiveReport 一次开播或下播通知
+type BilibiliLiveReport struct {
+	Event     BilibiliLiveEvent `json:"event"`
+	Room      BilibiliLiveRoom  `json:"room"`
+	StartedAt time.Time         `json:"startedAt"`
+	EndedAt   time.Time         `json:"endedAt"`
+	Duration  string            `json:"duration"`
+}
+
+// bilibiliLiveStateEntry 直播间上次通知时的状态
+type bilibiliLiveStateEntry struct {
+	RoomID    int64     `json:"roomId"`
+	Live      bool      `json:"live"`
+	Title     string    `json:"title"`
+	LiveSince time.Time `json:"liveSince"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// bilibiliLiveState UID → 直播间状态
+type bilibiliLiveState map[string]bilibiliLiveStateEntry
+
+// bilibiliLiveStatusInfo get_status_info_by_uids 接口返回的单个直播间
+type bilibiliLiveStatusInfo struct {
+	Title            string `json:"title"`
+	RoomID           int64  `json:"room_id"`
+	UID              int64  `json:"uid"`
+	Online           int64  `json:"online"`
+	LiveTime         int64  `json:"live_time"`
+	LiveStatus       int    `json:"live_status"`
+	Uname            string `json:"uname"`
+	AreaV2Name       string `json:"area_v2_name"`
+	AreaV2ParentName string `json:"area_v2_parent_name"`
+	CoverFromUser    string `json:"cover_from_user"`
+	Keyframe         string `json:"keyframe"`
+}
+
+// toRoom 转换为 BilibiliLiveRoom
+func (info bilibiliLiveStatusInfo) toRoom() BilibiliLiveRoom {
+	room := BilibiliLiveRoom{
+		UID:        strconv.FormatInt(info.UID, 10),
+		RoomID:     info.RoomID,
+		Username:   info.Uname,
+		Title:      info.Title,
+		Area:       info.AreaV2Name,
+		ParentArea: info.AreaV2ParentName,
+		Online:     info.Online,
+		Live:       info.LiveStatus == bilibiliLiveStatusLive,
+		CoverURL:   info.CoverFromUser,
+		RoomURL:    fmt.Sprintf("https://live.bilibili.com/%d", info.RoomID),
+	}
+	if room.CoverURL == "" {
+		room.CoverURL = info.Keyframe
+	}
+	if room.Live && info.LiveTime > 0 {
+		room.LiveSince = time.Unix(info.LiveTime, 0)
+	}
+	return room
+}
+
+// GetBilibiliLiveRooms 一次请求获取多个用户的直播间状态，返回 UID → 直播间，没有直播间的用户不在结果中
+func GetBilibiliLiveRooms(ctx context.Context, uids []string) (map[string]BilibiliLiveRoom, error) {
+	params := url.Values{}
+	for _, uid := range uids {
+		if _, err := strconv.ParseInt(uid, 10, 64); err != nil {
+			return nil, errors.Errorf("invalid Bilibili UID %q", uid)
+		}
+		params.Add("uids[]", uid)
+	}
+
+	var data json.RawMessage
+	if err := callBilibiliAPIAt(ctx, bilibiliLiveAPIBase, "/room/v1/Room/get_status_info_by_uids", params, false, "https://live.bilibili.com/", &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to get Bilibili live rooms")
+	}
+	return toBilibiliLiveRooms(data)
+}
+
+// toBilibiliLiveRooms 解析接口返回的 UID → 直播间；所有用户都没有直播间时 data 为 [] 而不是对象
+func toBilibiliLiveRooms(data json.RawMessage) (map[string]BilibiliLiveRoom, error) {
+	var infos map[string]bilibiliLiveStatusInfo
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 2 {
+		if err := json.Unmarshal(trimmed, &infos); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode Bilibili live rooms")
+		}
+	}
+
+	rooms := make(map[string]BilibiliLiveRoom, len(infos))
+	for uid, info := range infos {
+		if info.RoomID == 0 {
+			continue
+		}
+		rooms[uid] = info.toRoom()
+	}
+	return rooms, nil
+}
+
+// SendBilibiliLive 检查用户直播间的状态，开播和下播各通知一次
+func SendBilibiliLive(ctx context.Context, uids []string) error {
+	if len(uids) == 0 {
+		return errors.New("no Bilibili users to watch for live streams")
+	}
+
+	store := state.NewStore(config.GetDataDir())
+	known := bilibiliLiveState{}
+	if _, err := store.Load(bilibiliLiveStateName, &known); err != nil {
+		return err
+	}
+
+	rooms, err := GetBilibiliLiveRooms(ctx, uids)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, uid := range uids {
+		room, ok := rooms[uid]
+		if !ok {
+			slog.Info("Bilibili user has no live room", "uid", uid)
+			continue
+		}
+		if err := checkBilibiliLiveRoom(room, known); err != nil {
+			failed++
+			slog.Warn("Failed to check Bilibili live room", "uid", uid, "roomID", room.RoomID, "error", err)
+		}
+	}
+
+	if !IsDryRun() {
+		if err := store.Save(bilibiliLiveStateName, known); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to check %d of %d Bilibili live rooms", failed, len(uids))
+	}
+	return nil
+}
+
+func checkBilibiliLiveRoom(room BilibiliLiveRoom, known bilibiliLiveState) error {
+	now := time.Now()
+	previous, tracked := known[room.UID]
+	current := bilibiliLiveStateEntry{
+		RoomID:    room.RoomID,
+		Live:      room.Live,
+		Title:     room.Title,
+		LiveSince: room.LiveSince,
+		CheckedAt: now,
+	}
+
+	var report *BilibiliLiveReport
+	switch {
+	case room.Live && (!previous.Live || !previous.LiveSince.Equal(room.LiveSince)):
+		// 第一次检查时正在直播也通知；两次检查之间下播又开播时按新的一场通知
+		report = &BilibiliLiveReport{Event: BilibiliLiveEventStart, Room: room, StartedAt: room.LiveSince}
+	case !room.Live && tracked && previous.Live:
+		report = &BilibiliLiveReport{Event: BilibiliLiveEventEnd, Room: room, StartedAt: previous.LiveSince, EndedAt: now}
+		if report.Room.Title == "" {
+			report.Room.Title = previous.Title
+		}
+		if !previous.LiveSince.IsZero() {
+			report.Duration = formatDuration(int(now.Sub(previous.LiveSince).Seconds()))
+		}
+	}
+
+	if report == nil {
+		known[room.UID] = current
+		return nil
+	}
+
+	id := strings.Join([]string{strconv.FormatInt(room.RoomID, 10), string(report.Event), strconv.FormatInt(report.StartedAt.Unix(), 10)}, ":")
+	if err := publish(publication{
+		Task:     AzutvTaskTypeBilibiliLive,
+		Variant:  room.UID,
+		Username: ServiceNameBilibiliLive,
+		Items:    []history.Item{newHistoryItem(id, 1, room.Title, room.RoomURL, room.Username, report)},
+		Data:     report,
+	}); err != nil {
+		// 保留上次的状态，未发送的通知下次运行时重试
+		return err
+	}
+
+	known[room.UID] = current
+	return nil
+}
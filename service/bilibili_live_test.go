@@ -0,0 +1,53 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToBilibiliLiveRooms(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no live rooms", data: `[]`},
+		{name: "empty object", data: `{}`},
+		{name: "empty", data: ``},
+		{
+			name: "rooms",
+			data: `{"1":{"room_id":100,"uid":1,"uname":"a","live_status":1,"live_time":1700000000},` +
+				`"2":{"room_id":200,"uid":2,"uname":"b","live_status":0},` +
+				`"3":{"room_id":0,"uid":3}}`,
+			want: []string{"1", "2"},
+		},
+		{name: "unexpected array", data: `[{"room_id":100}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		rooms, err := toBilibiliLiveRooms(json.RawMessage(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rooms == nil {
+			t.Errorf("%s: rooms is nil, want empty map", tt.name)
+		}
+		if len(rooms) != len(tt.want) {
+			t.Errorf("%s: got %d rooms, want %d", tt.name, len(rooms), len(tt.want))
+		}
+		for _, uid := range tt.want {
+			if _, ok := rooms[uid]; !ok {
+				t.Errorf("%s: missing room of uid %s", tt.name, uid)
+			}
+		}
+	}
+
+	rooms, _ := toBilibiliLiveRooms(json.RawMessage(`{"1":{"room_id":100,"uid":1,"live_status":1,"live_time":1700000000}}`))
+	if room := rooms["1"]; !room.Live || room.LiveSince.Unix() != 1700000000 || room.RoomURL != "https://live.bilibili.com/100" {
+		t.Errorf("room = %+v", room)
+	}
+}
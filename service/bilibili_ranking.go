@@ -0,0 +1,343 @@
+package service
+
+import (
+	"azuserver/lib/history"
+	"azuserver/lib/workpool"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const ServiceNameBilibiliRanking = "Bilibili Ranking"
+
+// BilibiliRankingBoardWeekly 每周必看
+const BilibiliRankingBoardWeekly = "weekly"
+
+// bilibiliRankingBoard 排行榜对应的分区，URL 为网页版排行榜地址；
+// Region 为 true 时是二级分区，ranking/v2 只支持一级分区，改用 ranking/region 接口
+type bilibiliRankingBoard struct {
+	RID    int
+	Name   string
+	URL    string
+	Region bool
+}
+
+// bilibiliRankingBoards 排行榜名 → 分区，rid 为 0 时为全站；网页版没有单独的 VOCALOID·UTAU 排行榜
+var bilibiliRankingBoards = map[string]bilibiliRankingBoard{
+	"all":      {RID: 0, Name: "全站", URL: "https://www.bilibili.com/v/popular/rank/all"},
+	"anime":    {RID: 1, Name: "动画", URL: "https://www.bilibili.com/v/popular/rank/douga"},
+	"music":    {RID: 3, Name: "音乐", URL: "https://www.bilibili.com/v/popular/rank/music"},
+	"game":     {RID: 4, Name: "游戏", URL: "https://www.bilibili.com/v/popular/rank/game"},
+	"vocaloid": {RID: 30, Name: "VOCALOID·UTAU", URL: "https://www.bilibili.com/v/music/vocaloid", Region: true},
+}
+
+// bilibiliTimezone Bilibili 接口中不带时区的时间均为北京时间
+var bilibiliTimezone = time.FixedZone("UTC+8", 8*60*60)
+
+// defaultBilibiliRankingBoards 未配置时发送的排行榜
+var defaultBilibiliRankingBoards = []string{"all", BilibiliRankingBoardWeekly}
+
+// BilibiliRankingEntry 排行榜中的一个视频
+type BilibiliRankingEntry struct {
+	Rank          int       `json:"rank"`
+	BvID          string    `json:"bvid"`
+	AvID          string    `json:"avid"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	AuthorUID     string    `json:"authorUid"`
+	ViewCount     int64     `json:"viewCount"`
+	LikeCount     int64     `json:"likeCount"`
+	CoinCount     int64     `json:"coinCount"`
+	FavoriteCount int64     `json:"favoriteCount"`
+	ReplyCount    int64     `json:"replyCount"`
+	DanmakuCount  int64     `json:"danmakuCount"`
+	Score         int64     `json:"score"`
+	Duration      string    `json:"duration"`
+	PublishedAt   time.Time `json:"publishedAt"`
+	// 每周必看的推荐理由
+	Reason   string `json:"reason"`
+	CoverURL string `json:"coverUrl"`
+	VideoURL string `json:"videoUrl"`
+}
+
+// BilibiliRanking 一个排行榜，Label 为每周必看的期数
+type BilibiliRanking struct {
+	Board   string                 `json:"board"`
+	Name    string                 `json:"name"`
+	Label   string                 `json:"label"`
+	URL     string                 `json:"url"`
+	Entries []BilibiliRankingEntry `json:"entries"`
+}
+
+// bilibiliRankingItem ranking/v2 和 popular/series/one 接口返回的单个视频
+type bilibiliRankingItem struct {
+	Aid      int64  `json:"aid"`
+	Bvid     string `json:"bvid"`
+	Title    string `json:"title"`
+	Pic      string `json:"pic"`
+	Pubdate  int64  `json:"pubdate"`
+	Duration int    `json:"duration"`
+	Owner    struct {
+		Mid  int64  `json:"mid"`
+		Name string `json:"name"`
+	} `json:"owner"`
+	Stat struct {
+		View     int64 `json:"view"`
+		Danmaku  int64 `json:"danmaku"`
+		Reply    int64 `json:"reply"`
+		Favorite int64 `json:"favorite"`
+		Coin     int64 `json:"coin"`
+		Like     int64 `json:"like"`
+	} `json:"stat"`
+	Score      int64  `json:"score"`
+	RcmdReason string `json:"rcmd_reason"`
+}
+
+// toEntry 转换为排名为 rank 的 BilibiliRankingEntry
+func (item bilibiliRankingItem) toEntry(rank int) BilibiliRankingEntry {
+	entry := BilibiliRankingEntry{
+		Rank:          rank,
+		BvID:          item.Bvid,
+		AvID:          strconv.FormatInt(item.Aid, 10),
+		Title:         item.Title,
+		Author:        item.Owner.Name,
+		AuthorUID:     strconv.FormatInt(item.Owner.Mid, 10),
+		ViewCount:     item.Stat.View,
+		LikeCount:     item.Stat.Like,
+		CoinCount:     item.Stat.Coin,
+		FavoriteCount: item.Stat.Favorite,
+		ReplyCount:    item.Stat.Reply,
+		DanmakuCount:  item.Stat.Danmaku,
+		Score:         item.Score,
+		Reason:        item.RcmdReason,
+		CoverURL:      bilibiliURL(item.Pic),
+		VideoURL:      fmt.Sprintf("https://www.bilibili.com/video/%s", item.Bvid),
+	}
+	if item.Duration > 0 {
+		entry.Duration = formatDuration(item.Duration)
+	}
+	if item.Pubdate > 0 {
+		entry.PublishedAt = time.Unix(item.Pubdate, 0)
+	}
+	return entry
+}
+
+// bilibiliRegionRankingItem ranking/region 接口返回的单个视频，数值字段可能是字符串
+type bilibiliRegionRankingItem struct {
+	Aid         bilibiliFlexInt `json:"aid"`
+	Bvid        string          `json:"bvid"`
+	Title       string          `json:"title"`
+	Author      string          `json:"author"`
+	Mid         bilibiliFlexInt `json:"mid"`
+	Play        bilibiliFlexInt `json:"play"`
+	VideoReview bilibiliFlexInt `json:"video_review"` // 弹幕数
+	Review      bilibiliFlexInt `json:"review"`       // 评论数
+	Favorites   bilibiliFlexInt `json:"favorites"`
+	Coins       bilibiliFlexInt `json:"coins"`
+	Pts         bilibiliFlexInt `json:"pts"`
+	Duration    string          `json:"duration"` // 如 4:05
+	Create      string          `json:"create"`   // 如 2024-01-02 03:04
+	Pic         string          `json:"pic"`
+}
+
+// toEntry 转换为排名为 rank 的 BilibiliRankingEntry，该接口不提供点赞数
+func (item bilibiliRegionRankingItem) toEntry(rank int) BilibiliRankingEntry {
+	entry := BilibiliRankingEntry{
+		Rank:          rank,
+		BvID:          item.Bvid,
+		AvID:          strconv.FormatInt(int64(item.Aid), 10),
+		Title:         item.Title,
+		Author:        item.Author,
+		AuthorUID:     strconv.FormatInt(int64(item.Mid), 10),
+		ViewCount:     int64(item.Play),
+		CoinCount:     int64(item.Coins),
+		FavoriteCount: int64(item.Favorites),
+		ReplyCount:    int64(item.Review),
+		DanmakuCount:  int64(item.VideoReview),
+		Score:         int64(item.Pts),
+		Duration:      item.Duration,
+		CoverURL:      bilibiliURL(item.Pic),
+		VideoURL:      fmt.Sprintf("https://www.bilibili.com/video/%s", item.Bvid),
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", item.Create, bilibiliTimezone); err == nil {
+		entry.PublishedAt = t
+	}
+	return entry
+}
+
+func toBilibiliRankingEntries(items []bilibiliRankingItem) []BilibiliRankingEntry {
+	entries := make([]BilibiliRankingEntry, 0, len(items))
+	for _, item := range items {
+		if item.Bvid == "" {
+			continue
+		}
+		entries = append(entries, item.toEntry(len(entries)+1))
+	}
+	return entries
+}
+
+func toBilibiliRegionRankingEntries(items []bilibiliRegionRankingItem) []BilibiliRankingEntry {
+	entries := make([]BilibiliRankingEntry, 0, len(items))
+	for _, item := range items {
+		if item.Bvid == "" {
+			continue
+		}
+		entries = append(entries, item.toEntry(len(entries)+1))
+	}
+	return entries
+}
+
+// ParseBilibiliRankingBoards 校验排行榜名（all、anime、music、game、vocaloid、weekly），为空时返回默认的全站和每周必看
+func ParseBilibiliRankingBoards(boards []string) ([]string, error) {
+	if len(boards) == 0 {
+		return defaultBilibiliRankingBoards, nil
+	}
+	parsed := make([]string, 0, len(boards))
+	for _, board := range boards {
+		board = strings.ToLower(strings.TrimSpace(board))
+		if _, ok := bilibiliRankingBoards[board]; !ok && board != BilibiliRankingBoardWeekly {
+			return nil, errors.Errorf("invalid Bilibili ranking board %q (want all, anime, music, game, vocaloid or weekly)", board)
+		}
+		parsed = append(parsed, board)
+	}
+	return parsed, nil
+}
+
+// FetchBilibiliRanking 获取分区排行榜（近三日综合排行）
+func FetchBilibiliRanking(ctx context.Context, board string) (*BilibiliRanking, error) {
+	info, ok := bilibiliRankingBoards[board]
+	if !ok {
+		return nil, errors.Errorf("unknown Bilibili ranking board %q", board)
+	}
+	ranking := &BilibiliRanking{
+		Board: board,
+		Name:  info.Name,
+		URL:   info.URL,
+	}
+
+	if info.Region {
+		params := url.Values{
+			"rid":      {strconv.Itoa(info.RID)},
+			"day":      {"3"},
+			"original": {"0"},
+		}
+		var items []bilibiliRegionRankingItem
+		if err := callBilibiliAPI(ctx, "/x/web-interface/ranking/region", params, false, info.URL, &items); err != nil {
+			return nil, errors.Wrapf(err, "failed to get Bilibili %s ranking", board)
+		}
+		ranking.Entries = toBilibiliRegionRankingEntries(items)
+		return ranking, nil
+	}
+
+	params := url.Values{
+		"rid":  {strconv.Itoa(info.RID)},
+		"type": {"all"},
+	}
+	var data struct {
+		List []bilibiliRankingItem `json:"list"`
+	}
+	if err := callBilibiliAPI(ctx, "/x/web-interface/ranking/v2", params, true, info.URL, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to get Bilibili %s ranking", board)
+	}
+	ranking.Entries = toBilibiliRankingEntries(data.List)
+	return ranking, nil
+}
+
+// FetchBilibiliWeekly 获取最新一期每周必看
+func FetchBilibiliWeekly(ctx context.Context) (*BilibiliRanking, error) {
+	referer := "https://www.bilibili.com/v/popular/weekly"
+	var series struct {
+		List []struct {
+			Number  int    `json:"number"`
+			Subject string `json:"subject"`
+			Name    string `json:"name"`
+		} `json:"list"`
+	}
+	if err := callBilibiliAPI(ctx, "/x/web-interface/popular/series/list", nil, false, referer, &series); err != nil {
+		return nil, errors.Wrapf(err, "failed to get Bilibili weekly series")
+	}
+	if len(series.List) == 0 {
+		return nil, errors.New("Bilibili weekly series list is empty")
+	}
+	latest := series.List[0]
+
+	var data struct {
+		Config struct {
+			Label   string `json:"label"`
+			Subject string `json:"subject"`
+		} `json:"config"`
+		List []bilibiliRankingItem `json:"list"`
+	}
+	params := url.Values{"number": {strconv.Itoa(latest.Number)}}
+	if err := callBilibiliAPI(ctx, "/x/web-interface/popular/series/one", params, false, referer, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to get Bilibili weekly #%d", latest.Number)
+	}
+
+	label := data.Config.Label
+	if label == "" {
+		label = latest.Name
+	}
+	return &BilibiliRanking{
+		Board:   BilibiliRankingBoardWeekly,
+		Name:    "每周必看",
+		Label:   label,
+		URL:     fmt.Sprintf("%s?num=%d", referer, latest.Number),
+		Entries: toBilibiliRankingEntries(data.List),
+	}, nil
+}
+
+// SendBilibiliRanking 获取并发送各排行榜，每个排行榜单独发送
+func SendBilibiliRanking(ctx context.Context, boards []string) error {
+	boards, err := ParseBilibiliRankingBoards(boards)
+	if err != nil {
+		return err
+	}
+
+	// 并发获取各排行榜，之后按顺序发送
+	rankings := make([]*BilibiliRanking, len(boards))
+	errs := workpool.Run(ctx, len(boards), fetchWorkers, func(ctx context.Context, i int) error {
+		var err error
+		if boards[i] == BilibiliRankingBoardWeekly {
+			rankings[i], err = FetchBilibiliWeekly(ctx)
+		} else {
+			rankings[i], err = FetchBilibiliRanking(ctx, boards[i])
+		}
+		return err
+	})
+
+	var failed int
+	for i, board := range boards {
+		err := errs[i]
+		if err == nil {
+			err = publishBilibiliRanking(rankings[i])
+		}
+		if err != nil {
+			failed++
+			slog.Warn("Failed to send Bilibili ranking", "board", board, "error", err)
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to send %d of %d Bilibili rankings", failed, len(boards))
+	}
+	return nil
+}
+
+func publishBilibiliRanking(ranking *BilibiliRanking) error {
+	items := make([]history.Item, 0, len(ranking.Entries))
+	for _, entry := range ranking.Entries {
+		items = append(items, newHistoryItem(entry.BvID, entry.Rank, entry.Title, entry.VideoURL, entry.Author, entry))
+	}
+	return publish(publication{
+		Task:     AzutvTaskTypeBilibiliRanking,
+		Variant:  ranking.Board,
+		Username: ServiceNameBilibiliRanking,
+		Items:    items,
+		Data:     ranking,
+	})
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestToBilibiliRegionRankingEntries(t *testing.T) {
+	// ranking/region 中 aid、play 等字段可能是字符串，播放数隐藏时为 "--"
+	const data = `[
+		{"aid":"170001","bvid":"BV17x411w7KC","title":"曲","author":"P主","mid":123,"play":45678,"video_review":"890","review":12,"favorites":3456,"coins":789,"pts":99999,"duration":"4:05","create":"2024-01-02 03:04","pic":"//i0.hdslb.com/a.jpg"},
+		{"aid":170002,"bvid":"BV1xx411c7mD","title":"隐藏","author":"B","mid":"456","play":"--","duration":"1:00","create":""},
+		{"aid":0,"bvid":"","title":"无效"}
+	]`
+	var items []bilibiliRegionRankingItem
+	if err := json.Unmarshal([]byte(data), &items); err != nil {
+		t.Fatal(err)
+	}
+	entries := toBilibiliRegionRankingEntries(items)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	first := entries[0]
+	wantTime := time.Date(2024, 1, 2, 3, 4, 0, 0, bilibiliTimezone)
+	if first.Rank != 1 || first.AvID != "170001" || first.AuthorUID != "123" || first.ViewCount != 45678 ||
+		first.DanmakuCount != 890 || first.ReplyCount != 12 || first.FavoriteCount != 3456 || first.CoinCount != 789 ||
+		first.Score != 99999 || first.Duration != "4:05" || !first.PublishedAt.Equal(wantTime) ||
+		first.CoverURL != "https://i0.hdslb.com/a.jpg" || first.VideoURL != "https://www.bilibili.com/video/BV17x411w7KC" {
+		t.Errorf("unexpected first entry: %+v", first)
+	}
+
+	second := entries[1]
+	if second.Rank != 2 || second.AvID != "170002" || second.AuthorUID != "456" || second.ViewCount != 0 || !second.PublishedAt.IsZero() {
+		t.Errorf("unexpected second entry: %+v", second)
+	}
+}
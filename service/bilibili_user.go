@@ -2,10 +2,15 @@ package service
 
 import (
 	"azuserver/config"
-	"encoding/json"
+	"azuserver/lib/browser"
+	"azuserver/lib/history"
+	"azuserver/lib/workpool"
+	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,53 +23,101 @@ const ServiceNameBilibiliUser = "Bilibili User Info"
 
 // BilibiliUserInfo 存储用户基本信息
 type BilibiliUserInfo struct {
-	UserID      string
-	Username    string
-	FollowerCount int64    // 粉丝数
-	FollowingCount int64   // 关注数
-	LikeCount   int64     // 获赞数
-	PlayCount   int64     // 播放数
-	VideoCount  int       // 视频数
-	Description string
-	AvatarURL   string
-	SpaceURL    string
-	Level       int
-	VipType     int       // 0:无 1:月度 2:年度
+	UserID         string            `json:"userId"`
+	Username       string            `json:"username"`
+	FollowerCount  int64             `json:"followerCount"`  // 粉丝数
+	FollowingCount int64             `json:"followingCount"` // 关注数
+	LikeCount      int64             `json:"likeCount"`      // 获赞数
+	PlayCount      int64             `json:"playCount"`      // 播放数
+	VideoCount     int               `json:"videoCount"`     // 视频数
+	Description    string            `json:"description"`
+	AvatarURL      string            `json:"avatarUrl"`
+	SpaceURL       string            `json:"spaceUrl"`
+	Level          int               `json:"level"`
+	VipType        int               `json:"vipType"`           // 0:无 1:月度 2:年度
+	Sources        map[string]string `json:"sources,omitempty"` // 字段名 → 提供该字段的数据来源
+}
+
+// Bilibili 用户信息的数据来源
+const (
+	bilibiliSourcePage         = "page"
+	bilibiliSourceAccInfo      = "acc/info"
+	bilibiliSourceRelationStat = "relation/stat"
+	bilibiliSourceUpStat       = "upstat"
+	bilibiliSourceArcSearch    = "arc/search"
+	bilibiliSourceBrowser      = "browser"
+)
+
+// setSource 记录字段的数据来源，后写入的来源覆盖先前的
+func (u *BilibiliUserInfo) setSource(field string, source string) {
+	if u.Sources == nil {
+		u.Sources = make(map[string]string)
+	}
+	u.Sources[field] = source
 }
 
 // BilibiliVideoInfo 存储视频信息  
 type BilibiliVideoInfo struct {
-	BvID        string
-	AvID        string
-	Title       string
-	ViewCount   int64
-	LikeCount   int64
-	CoinCount   int64     // 投币数
-	FavoriteCount int64   // 收藏数
-	ShareCount  int64     // 分享数
-	ReplyCount  int64     // 评论数
-	UploadDate  string
-	Duration    string
-	Description string
-	CoverURL    string
-	VideoURL    string
-	Author      string
+	BvID             string                  `json:"bvid"`
+	AvID             string                  `json:"avid"`
+	Title            string                  `json:"title"`
+	ViewCount        int64                   `json:"viewCount"`
+	LikeCount        int64                   `json:"likeCount"`
+	CoinCount        int64                   `json:"coinCount"`     // 投币数
+	FavoriteCount    int64                   `json:"favoriteCount"` // 收藏数
+	ShareCount       int64                   `json:"shareCount"`    // 分享数
+	ReplyCount       int64                   `json:"replyCount"`    // 评论数
+	UploadDate       string                  `json:"uploadDate"`
+	PublishedAt      time.Time               `json:"publishedAt"`
+	Duration         string                  `json:"duration"`
+	Description      string                  `json:"description"`
+	CoverURL         string                  `json:"coverUrl"`
+	VideoURL         string                  `json:"videoUrl"`
+	Author           string                  `json:"author"`
+	Parts            []BilibiliVideoPart     `json:"parts,omitempty"`            // 分P，只有一个分P时为空
+	CID              int64                   `json:"cid"`                        // 第一个分P的cid，弹幕按它获取
+	DanmakuCount     int64                   `json:"danmakuCount"`               // 弹幕总数
+	TopComments      []BilibiliComment       `json:"topComments,omitempty"`      // 点赞最多的评论，选择 comments 统计时获取
+	DanmakuHistogram []BilibiliDanmakuBucket `json:"danmakuHistogram,omitempty"` // 第一个分P的弹幕时间分布，选择 danmaku 统计时获取
+	DanmakuSampled   int                     `json:"danmakuSampled,omitempty"`   // 弹幕分布统计到的弹幕数，弹幕池有上限，可能少于 DanmakuCount
+
+	cidSeconds int // 第一个分P的时长（秒），用于划分弹幕分布
+}
+
+// BilibiliVideoPart 多P视频的一个分P
+type BilibiliVideoPart struct {
+	Page     int    `json:"page"`
+	Title    string `json:"title"`
+	Duration string `json:"duration"`
+	URL      string `json:"url"`
+}
+
+// BilibiliUserReport 用户信息及其最新视频
+type BilibiliUserReport struct {
+	User     *BilibiliUserInfo     `json:"user"`
+	Videos   []BilibiliVideoInfo   `json:"videos"`
+	Sections []BilibiliUserSection `json:"sections,omitempty"`
+}
+
+// HasSection 报告是否包含指定的附加统计，供模板使用
+func (r BilibiliUserReport) HasSection(section string) bool {
+	return slices.Contains(r.Sections, BilibiliUserSection(section))
 }
 
 // GetBilibiliUserInfo 根据用户UID获取Bilibili用户信息
-func GetBilibiliUserInfo(uid string) (*BilibiliUserInfo, error) {
+func GetBilibiliUserInfo(ctx context.Context, uid string) (*BilibiliUserInfo, error) {
 	userInfo := &BilibiliUserInfo{
 		UserID:   uid,
 		SpaceURL: fmt.Sprintf("https://space.bilibili.com/%s", uid),
 	}
 
 	// 首先尝试通过页面获取基本信息（用户名、头像等）
-	if err := getBilibiliUserBasicInfo(userInfo); err != nil {
+	if err := getBilibiliUserBasicInfo(ctx, userInfo); err != nil {
 		slog.Warn("Failed to get basic user info from page", "uid", uid, "error", err)
 	}
 
 	// 通过API获取统计信息
-	if err := getBilibiliUserStatsFromAPI(userInfo); err != nil {
+	if err := getBilibiliUserStatsFromAPI(ctx, userInfo); err != nil {
 		slog.Warn("Failed to get user stats from API", "uid", uid, "error", err)
 	}
 
@@ -72,10 +125,11 @@ func GetBilibiliUserInfo(uid string) (*BilibiliUserInfo, error) {
 }
 
 // getBilibiliUserBasicInfo 从用户页面获取基本信息
-func getBilibiliUserBasicInfo(userInfo *BilibiliUserInfo) error {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
+func getBilibiliUserBasicInfo(ctx context.Context, userInfo *BilibiliUserInfo) error {
+	if err := waitForURL(ctx, userInfo.SpaceURL); err != nil {
+		return err
+	}
+	c := newBilibiliCollector(ctx)
 
 	// 从页面标题获取用户名
 	c.OnHTML("title", func(e *colly.HTMLElement) {
@@ -84,6 +138,7 @@ func getBilibiliUserBasicInfo(userInfo *BilibiliUserInfo) error {
 			parts := strings.Split(title, "的个人空间")
 			if len(parts) > 0 && userInfo.Username == "" {
 				userInfo.Username = parts[0]
+				userInfo.setSource("username", bilibiliSourcePage)
 			}
 		}
 	})
@@ -100,6 +155,7 @@ func getBilibiliUserBasicInfo(userInfo *BilibiliUserInfo) error {
 						contentParts := strings.Split(detail, "内容，关注")
 						if len(contentParts) > 0 {
 							userInfo.Description = strings.TrimSpace(contentParts[0])
+							userInfo.setSource("description", bilibiliSourcePage)
 						}
 					}
 				}
@@ -161,132 +217,199 @@ func getBilibiliUserBasicInfo(userInfo *BilibiliUserInfo) error {
 	return c.Visit(userInfo.SpaceURL)
 }
 
-// getBilibiliUserStatsFromAPI 通过API获取用户统计信息
-func getBilibiliUserStatsFromAPI(userInfo *BilibiliUserInfo) error {
-	// 先尝试传统 API 方法
-	if err := tryBilibiliAPI(userInfo); err == nil {
+// getBilibiliUserStatsFromAPI 通过API获取用户资料和统计信息，各接口的结果合并到 userInfo
+func getBilibiliUserStatsFromAPI(ctx context.Context, userInfo *BilibiliUserInfo) error {
+	if err := tryBilibiliAPI(ctx, userInfo); err != nil {
+		slog.Warn("Failed to get user info from acc/info", "uid", userInfo.UserID, "error", err)
+	}
+	relationErr := getBilibiliRelationStat(ctx, userInfo)
+	if relationErr != nil {
+		slog.Warn("Failed to get user relation stat", "uid", userInfo.UserID, "error", relationErr)
+	}
+	upStatErr := getBilibiliUpStat(ctx, userInfo)
+	if upStatErr != nil {
+		slog.Warn("Failed to get user upstat", "uid", userInfo.UserID, "error", upStatErr)
+	}
+
+	slog.Info("Got Bilibili user stats",
+		"uid", userInfo.UserID,
+		"name", userInfo.Username,
+		"followers", userInfo.FollowerCount,
+		"following", userInfo.FollowingCount,
+		"likes", userInfo.LikeCount,
+		"plays", userInfo.PlayCount,
+		"sources", userInfo.Sources)
+	if relationErr == nil && upStatErr == nil {
 		return nil
 	}
 
-	// API 失败，尝试无头浏览器方法
-	return getBilibiliUserStatsWithBrowser(userInfo)
+	// 统计接口失败，尝试无头浏览器方法补全
+	err := getBilibiliUserStatsWithBrowser(ctx, userInfo)
+	if errors.Is(err, browser.ErrBrowserUnavailable) {
+		slog.Info("Skipping browser fallback for Bilibili stats", "uid", userInfo.UserID, "reason", err)
+	}
+	return err
 }
 
-// tryBilibiliAPI 尝试传统 API 方法
-func tryBilibiliAPI(userInfo *BilibiliUserInfo) error {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
-
-	c.OnRequest(func(r *colly.Request) {
-		r.Headers.Set("Referer", userInfo.SpaceURL)
-		r.Headers.Set("Accept", "application/json, text/plain, */*")
-	})
-
-	var apiSuccess bool
-	c.OnResponse(func(r *colly.Response) {
-		var apiResp struct {
-			Code int `json:"code"`
-			Data struct {
-				Mid       int64  `json:"mid"`
-				Name      string `json:"name"`
-				Sex       string `json:"sex"`
-				Face      string `json:"face"`
-				Sign      string `json:"sign"`
-				Level     int    `json:"level"`
-				Follower  int64  `json:"follower"`
-				Following int64  `json:"following"`
-			} `json:"data"`
-		}
+// tryBilibiliAPI 通过带 WBI 签名的 acc/info 接口获取用户资料
+func tryBilibiliAPI(ctx context.Context, userInfo *BilibiliUserInfo) error {
+	var data struct {
+		Mid       int64  `json:"mid"`
+		Name      string `json:"name"`
+		Sex       string `json:"sex"`
+		Face      string `json:"face"`
+		Sign      string `json:"sign"`
+		Level     int    `json:"level"`
+		Follower  int64  `json:"follower"`
+		Following int64  `json:"following"`
+		Vip       struct {
+			Type int `json:"type"`
+		} `json:"vip"`
+	}
+	params := url.Values{"mid": {userInfo.UserID}}
+	if err := callBilibiliAPI(ctx, "/x/space/wbi/acc/info", params, true, userInfo.SpaceURL, &data); err != nil {
+		return err
+	}
 
-		if err := json.Unmarshal(r.Body, &apiResp); err != nil {
-			slog.Error("Failed to parse API response", "error", err)
-			return
-		}
+	if userInfo.Username == "" && data.Name != "" {
+		userInfo.Username = data.Name
+		userInfo.setSource("username", bilibiliSourceAccInfo)
+	}
+	if userInfo.AvatarURL == "" && data.Face != "" {
+		userInfo.AvatarURL = data.Face
+		userInfo.setSource("avatar", bilibiliSourceAccInfo)
+	}
+	if userInfo.Description == "" && data.Sign != "" {
+		userInfo.Description = data.Sign
+		userInfo.setSource("description", bilibiliSourceAccInfo)
+	}
+	if userInfo.Level == 0 && data.Level > 0 {
+		userInfo.Level = data.Level
+		userInfo.setSource("level", bilibiliSourceAccInfo)
+	}
+	if userInfo.VipType == 0 && data.Vip.Type > 0 {
+		userInfo.VipType = data.Vip.Type
+		userInfo.setSource("vipType", bilibiliSourceAccInfo)
+	}
+	// acc/info 已不再返回粉丝数，只在有值时使用，relation/stat 的结果优先
+	if data.Follower > 0 {
+		userInfo.FollowerCount = data.Follower
+		userInfo.setSource("follower", bilibiliSourceAccInfo)
+	}
+	if data.Following > 0 {
+		userInfo.FollowingCount = data.Following
+		userInfo.setSource("following", bilibiliSourceAccInfo)
+	}
+	return nil
+}
 
-		if apiResp.Code == 0 {
-			apiSuccess = true
-			if userInfo.Username == "" {
-				userInfo.Username = apiResp.Data.Name
-			}
-			if userInfo.AvatarURL == "" {
-				userInfo.AvatarURL = apiResp.Data.Face
-			}
-			if userInfo.Description == "" {
-				userInfo.Description = apiResp.Data.Sign
-			}
-			if userInfo.Level == 0 {
-				userInfo.Level = apiResp.Data.Level
-			}
-			userInfo.FollowerCount = apiResp.Data.Follower
-			userInfo.FollowingCount = apiResp.Data.Following
-
-			slog.Info("Successfully got user stats from API",
-				"uid", userInfo.UserID,
-				"name", userInfo.Username,
-				"followers", userInfo.FollowerCount,
-				"following", userInfo.FollowingCount)
-		} else {
-			slog.Warn("API returned error, will fallback to browser method", "code", apiResp.Code)
-		}
-	})
+// getBilibiliRelationStat 通过 relation/stat 接口获取粉丝数和关注数
+func getBilibiliRelationStat(ctx context.Context, userInfo *BilibiliUserInfo) error {
+	var data struct {
+		Mid       int64 `json:"mid"`
+		Following int64 `json:"following"`
+		Follower  int64 `json:"follower"`
+	}
+	params := url.Values{"vmid": {userInfo.UserID}}
+	if err := callBilibiliAPI(ctx, "/x/relation/stat", params, false, userInfo.SpaceURL, &data); err != nil {
+		return err
+	}
 
-	c.OnError(func(r *colly.Response, err error) {
-		slog.Warn("API request failed, will fallback to browser method", "url", r.Request.URL, "error", err)
-	})
+	userInfo.FollowerCount = data.Follower
+	userInfo.setSource("follower", bilibiliSourceRelationStat)
+	userInfo.FollowingCount = data.Following
+	userInfo.setSource("following", bilibiliSourceRelationStat)
+	return nil
+}
 
-	apiURL := fmt.Sprintf("https://api.bilibili.com/x/space/wbi/acc/info?mid=%s", userInfo.UserID)
-	c.Visit(apiURL)
+// getBilibiliUpStat 通过 space/upstat 接口获取获赞数和视频播放数
+func getBilibiliUpStat(ctx context.Context, userInfo *BilibiliUserInfo) error {
+	var data struct {
+		Archive struct {
+			View int64 `json:"view"`
+		} `json:"archive"`
+		Article struct {
+			View int64 `json:"view"`
+		} `json:"article"`
+		Likes int64 `json:"likes"`
+	}
+	params := url.Values{"mid": {userInfo.UserID}}
+	if err := callBilibiliAPI(ctx, "/x/space/upstat", params, false, userInfo.SpaceURL, &data); err != nil {
+		return err
+	}
 
-	if apiSuccess {
-		return nil
+	// 未登录时该接口可能返回空对象，此时不视为有效数据
+	if data.Likes == 0 && data.Archive.View == 0 {
+		return errors.Errorf("Bilibili upstat returned no data for uid %s", userInfo.UserID)
 	}
-	return errors.New("API method failed")
+	userInfo.LikeCount = data.Likes
+	userInfo.setSource("like", bilibiliSourceUpStat)
+	userInfo.PlayCount = data.Archive.View
+	userInfo.setSource("play", bilibiliSourceUpStat)
+	return nil
 }
 
-// getBilibiliUserStatsWithBrowser 使用无头浏览器获取统计数据
-func getBilibiliUserStatsWithBrowser(userInfo *BilibiliUserInfo) error {
-	// 导入检查 - 如果 chromedp 不可用，返回错误
-	// 这里我们在运行时检查，而不是编译时
-	defer func() {
-		if r := recover(); r != nil {
-			slog.Warn("Browser method not available (chromedp not installed)", "error", r)
-		}
-	}()
+// getBilibiliUserStatsWithBrowser 使用无头浏览器渲染空间页面获取统计数据，只补全其他来源未提供的字段；
+// 本机没有浏览器时返回包装了 browser.ErrBrowserUnavailable 的错误
+func getBilibiliUserStatsWithBrowser(ctx context.Context, userInfo *BilibiliUserInfo) error {
+	hb, err := browser.NewHeadlessBrowser(ctx, config.GetBrowserPath())
+	if err != nil {
+		return err
+	}
+	defer hb.Close()
+	if err := hb.SetCookies(bilibiliCookies(), ".bilibili.com"); err != nil {
+		slog.Warn("Failed to set Bilibili cookies in browser", "error", err)
+	}
 
-	// 这里会在安装 chromedp 后启用
 	slog.Info("Attempting to get stats using headless browser", "uid", userInfo.UserID)
-
-	// TODO: 实际使用浏览器的代码在这里
-	/*
-	browser := lib_browser.NewHeadlessBrowser()
-	defer browser.Close()
-
-	stats, err := browser.ExtractBilibiliStats(userInfo.UserID)
+	if err := waitForURL(ctx, userInfo.SpaceURL); err != nil {
+		return err
+	}
+	stats, err := hb.ExtractBilibiliStats(userInfo.UserID)
 	if err != nil {
 		return errors.Wrapf(err, "failed to extract stats with browser for uid %s", userInfo.UserID)
 	}
 
 	// 更新用户信息
-	if username := lib_browser.GetStringFromResult(stats, "username"); username != "" && userInfo.Username == "" {
+	if username := browser.GetStringFromResult(stats, "username"); username != "" && userInfo.Username == "" {
 		userInfo.Username = username
+		userInfo.setSource("username", bilibiliSourceBrowser)
 	}
-	if desc := lib_browser.GetStringFromResult(stats, "description"); desc != "" && userInfo.Description == "" {
+	if desc := browser.GetStringFromResult(stats, "description"); desc != "" && userInfo.Description == "" {
 		userInfo.Description = desc
+		userInfo.setSource("description", bilibiliSourceBrowser)
 	}
-	if face := lib_browser.GetStringFromResult(stats, "face"); face != "" && userInfo.AvatarURL == "" {
+	if face := browser.GetStringFromResult(stats, "face"); face != "" && userInfo.AvatarURL == "" {
 		userInfo.AvatarURL = face
+		userInfo.setSource("avatar", bilibiliSourceBrowser)
 	}
-
-	userInfo.FollowerCount = lib_browser.GetIntFromResult(stats, "follower")
-	userInfo.FollowingCount = lib_browser.GetIntFromResult(stats, "following")
-	userInfo.LikeCount = lib_browser.GetIntFromResult(stats, "like")
-	userInfo.PlayCount = lib_browser.GetIntFromResult(stats, "play")
-	if level := lib_browser.GetIntFromResult(stats, "level"); level > 0 && userInfo.Level == 0 {
+	if level := browser.GetIntFromResult(stats, "level"); level > 0 && userInfo.Level == 0 {
 		userInfo.Level = int(level)
+		userInfo.setSource("level", bilibiliSourceBrowser)
 	}
-	if vipType := lib_browser.GetIntFromResult(stats, "vipType"); vipType > 0 && userInfo.VipType == 0 {
+	if vipType := browser.GetIntFromResult(stats, "vipType"); vipType > 0 && userInfo.VipType == 0 {
 		userInfo.VipType = int(vipType)
+		userInfo.setSource("vipType", bilibiliSourceBrowser)
+	}
+
+	// 统计数据只补全接口未提供的字段
+	counts := []struct {
+		field string
+		value *int64
+	}{
+		{"follower", &userInfo.FollowerCount},
+		{"following", &userInfo.FollowingCount},
+		{"like", &userInfo.LikeCount},
+		{"play", &userInfo.PlayCount},
+	}
+	for _, count := range counts {
+		if _, ok := userInfo.Sources[count.field]; ok {
+			continue
+		}
+		if value := browser.GetIntFromResult(stats, count.field); value > 0 {
+			*count.value = value
+			userInfo.setSource(count.field, bilibiliSourceBrowser)
+		}
 	}
 
 	slog.Info("Successfully got user stats with browser",
@@ -296,249 +419,199 @@ func getBilibiliUserStatsWithBrowser(userInfo *BilibiliUserInfo) error {
 		"following", userInfo.FollowingCount,
 		"likes", userInfo.LikeCount,
 		"plays", userInfo.PlayCount)
-	*/
-
-	slog.Warn("Browser method is not enabled yet - please install chromedp dependency")
-	return errors.New("browser method not available")
+	return nil
 }
 
-// GetBilibiliUserVideos 获取用户最新的视频列表
-func GetBilibiliUserVideos(uid string, limit int) ([]BilibiliVideoInfo, error) {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
+// BilibiliVideoOrder 投稿列表的排序方式
+type BilibiliVideoOrder string
 
-	var videos []BilibiliVideoInfo
-	videosURL := fmt.Sprintf("https://space.bilibili.com/%s/video", uid)
+const (
+	BilibiliVideoOrderPubdate BilibiliVideoOrder = "pubdate" // 最新发布
+	BilibiliVideoOrderClick   BilibiliVideoOrder = "click"   // 最多播放
+	BilibiliVideoOrderStow    BilibiliVideoOrder = "stow"    // 最多收藏
+)
 
-	c.OnHTML("script", func(e *colly.HTMLElement) {
-		scriptContent := e.Text
-		
-		// 在页面脚本中查找视频数据
-		if strings.Contains(scriptContent, "__INITIAL_STATE__") || strings.Contains(scriptContent, "window.__INITIAL_STATE__") {
-			// 提取视频列表数据
-			if strings.Contains(scriptContent, "\"list\":[") && strings.Contains(scriptContent, "\"bvid\":") {
-				// 提取BV号
-				bvidRegex := regexp.MustCompile(`"bvid":"([^"]+)"`)
-				bvids := bvidRegex.FindAllStringSubmatch(scriptContent, limit)
-				
-				// 提取标题
-				titleRegex := regexp.MustCompile(`"title":"([^"]+)"`)
-				titles := titleRegex.FindAllStringSubmatch(scriptContent, limit)
-				
-				// 提取播放数
-				playRegex := regexp.MustCompile(`"play":(\d+)`)
-				plays := playRegex.FindAllStringSubmatch(scriptContent, limit)
-				
-				// 提取创建时间（Unix时间戳）
-				createdRegex := regexp.MustCompile(`"created":(\d+)`)
-				createds := createdRegex.FindAllStringSubmatch(scriptContent, limit)
-				
-				// 提取时长（秒）
-				lengthRegex := regexp.MustCompile(`"length":"([^"]+)"`)
-				lengths := lengthRegex.FindAllStringSubmatch(scriptContent, limit)
-				
-				// 提取描述
-				descRegex := regexp.MustCompile(`"description":"([^"]*)"`)
-				descs := descRegex.FindAllStringSubmatch(scriptContent, limit)
-				
-				// 提取封面图
-				picRegex := regexp.MustCompile(`"pic":"([^"]+)"`)
-				pics := picRegex.FindAllStringSubmatch(scriptContent, limit)
-
-				// 组合视频信息
-				maxLen := len(bvids)
-				if limit > 0 && maxLen > limit {
-					maxLen = limit
-				}
+// bilibiliVideoMaxPageSize arc/search 接口每页最多返回的视频数
+const bilibiliVideoMaxPageSize = 50
+
+// ParseBilibiliVideoOrder 解析排序方式，空字符串为按发布时间排序
+func ParseBilibiliVideoOrder(order string) (BilibiliVideoOrder, error) {
+	switch o := BilibiliVideoOrder(strings.ToLower(strings.TrimSpace(order))); o {
+	case "":
+		return BilibiliVideoOrderPubdate, nil
+	case BilibiliVideoOrderPubdate, BilibiliVideoOrderClick, BilibiliVideoOrderStow:
+		return o, nil
+	default:
+		return "", errors.Errorf("invalid Bilibili video order %q (want pubdate, click or stow)", order)
+	}
+}
 
-				for i := 0; i < maxLen && i < len(titles); i++ {
-					video := BilibiliVideoInfo{}
-					
-					if len(bvids[i]) > 1 {
-						video.BvID = bvids[i][1]
-						video.VideoURL = fmt.Sprintf("https://www.bilibili.com/video/%s", video.BvID)
-					}
-					
-					if len(titles[i]) > 1 {
-						video.Title = titles[i][1]
-					}
-					
-					if i < len(plays) && len(plays[i]) > 1 {
-						if count, err := strconv.ParseInt(plays[i][1], 10, 64); err == nil {
-							video.ViewCount = count
-						}
-					}
-					
-					if i < len(createds) && len(createds[i]) > 1 {
-						if timestamp, err := strconv.ParseInt(createds[i][1], 10, 64); err == nil {
-							video.UploadDate = time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
-						}
-					}
-					
-					if i < len(lengths) && len(lengths[i]) > 1 {
-						video.Duration = lengths[i][1]
-					}
-					
-					if i < len(descs) && len(descs[i]) > 1 {
-						video.Description = descs[i][1]
-					}
-					
-					if i < len(pics) && len(pics[i]) > 1 {
-						video.CoverURL = "https:" + pics[i][1]
-					}
+// BilibiliVideoQuery 投稿列表的分页和排序参数，Page 从 1 开始
+type BilibiliVideoQuery struct {
+	Page     int
+	PageSize int
+	Order    BilibiliVideoOrder
+}
 
-					if video.BvID != "" {
-						videos = append(videos, video)
-					}
-				}
-			}
-		}
-	})
+// BilibiliVideoPage 一页投稿列表，Total 为用户的投稿总数
+type BilibiliVideoPage struct {
+	Page     int
+	PageSize int
+	Total    int
+	Videos   []BilibiliVideoInfo
+}
 
-	c.OnError(func(r *colly.Response, err error) {
-		slog.Error("Bilibili videos scraping error", "url", r.Request.URL, "error", err)
-	})
+// bilibiliArcSearchVideo arc/search 接口返回的单个视频
+type bilibiliArcSearchVideo struct {
+	Aid         int64           `json:"aid"`
+	Bvid        string          `json:"bvid"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Pic         string          `json:"pic"`
+	Author      string          `json:"author"`
+	Play        bilibiliFlexInt `json:"play"`
+	Comment     int64           `json:"comment"`
+	VideoReview int64           `json:"video_review"` // 弹幕数
+	Created     int64           `json:"created"`
+	Length      string          `json:"length"`
+}
+
+// bilibiliFlexInt 兼容接口中以数字或字符串（如隐藏播放数时的 "--"）返回的计数
+type bilibiliFlexInt int64
 
-	err := c.Visit(videosURL)
+func (n *bilibiliFlexInt) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	value, err := strconv.ParseInt(raw, 10, 64)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to visit Bilibili videos page: %s", videosURL)
+		// 无法解析的值视为 0
+		*n = 0
+		return nil
 	}
+	*n = bilibiliFlexInt(value)
+	return nil
+}
 
-	return videos, nil
+// toVideoInfo 转换为 BilibiliVideoInfo
+func (v bilibiliArcSearchVideo) toVideoInfo() BilibiliVideoInfo {
+	video := BilibiliVideoInfo{
+		BvID:        v.Bvid,
+		AvID:        strconv.FormatInt(v.Aid, 10),
+		Title:       v.Title,
+		ViewCount:   int64(v.Play),
+		ReplyCount:  v.Comment,
+		Duration:    v.Length,
+		Description: v.Description,
+		CoverURL:    v.Pic,
+		VideoURL:    fmt.Sprintf("https://www.bilibili.com/video/%s", v.Bvid),
+		Author:      v.Author,
+	}
+	if strings.HasPrefix(video.CoverURL, "//") {
+		video.CoverURL = "https:" + video.CoverURL
+	}
+	if v.Created > 0 {
+		video.PublishedAt = time.Unix(v.Created, 0)
+		video.UploadDate = video.PublishedAt.In(config.GetLocation()).Format("2006-01-02 15:04:05")
+	}
+	return video
 }
 
-// GetBilibiliVideoDetails 获取单个视频的详细信息
-func GetBilibiliVideoDetails(bvid string) (*BilibiliVideoInfo, error) {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
+// GetBilibiliUserVideos 通过带 WBI 签名的 arc/search 接口获取用户的投稿列表
+func GetBilibiliUserVideos(ctx context.Context, uid string, query BilibiliVideoQuery) (*BilibiliVideoPage, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 || query.PageSize > bilibiliVideoMaxPageSize {
+		query.PageSize = bilibiliVideoMaxPageSize
+	}
+	if query.Order == "" {
+		query.Order = BilibiliVideoOrderPubdate
+	}
 
-	video := &BilibiliVideoInfo{
-		BvID:     bvid,
-		VideoURL: fmt.Sprintf("https://www.bilibili.com/video/%s", bvid),
+	params := url.Values{
+		"mid":   {uid},
+		"pn":    {strconv.Itoa(query.Page)},
+		"ps":    {strconv.Itoa(query.PageSize)},
+		"order": {string(query.Order)},
+		// 缺少以下浏览器指纹参数时接口容易返回 -352
+		"platform":         {"web"},
+		"dm_img_list":      {"[]"},
+		"dm_img_str":       {"V2ViR0wgMS4wIChPcGVuR0wgRVMgMi4wIENocm9taXVtKQ"},
+		"dm_cover_img_str": {"QU5HTEUgKEludGVsLCBJbnRlbChSKSBVSEQgR3JhcGhpY3MgKDB4MDAwMDlCQzQpIERpcmVjdDNEMTEgdnNfNV8wIHBzXzVfMCwgRDNEMTEpR29vZ2xlIEluYy4gKEludGVsKQ"},
+	}
+	var data struct {
+		List struct {
+			Vlist []bilibiliArcSearchVideo `json:"vlist"`
+		} `json:"list"`
+		Page struct {
+			Pn    int `json:"pn"`
+			Ps    int `json:"ps"`
+			Count int `json:"count"`
+		} `json:"page"`
+	}
+	referer := fmt.Sprintf("https://space.bilibili.com/%s/video", uid)
+	if err := callBilibiliAPI(ctx, "/x/space/wbi/arc/search", params, true, referer, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to get Bilibili videos for uid %s", uid)
 	}
 
-	// 获取视频详细信息
+	page := &BilibiliVideoPage{
+		Page:     query.Page,
+		PageSize: query.PageSize,
+		Total:    data.Page.Count,
+		Videos:   make([]BilibiliVideoInfo, 0, len(data.List.Vlist)),
+	}
+	for _, v := range data.List.Vlist {
+		if v.Bvid == "" {
+			continue
+		}
+		page.Videos = append(page.Videos, v.toVideoInfo())
+	}
+	return page, nil
+}
+
+// GetBilibiliVideoDetails 获取单个视频的详细信息，数据取自视频页面 __INITIAL_STATE__ 中的 videoData
+func GetBilibiliVideoDetails(ctx context.Context, bvid string) (*BilibiliVideoInfo, error) {
+	videoURL := fmt.Sprintf("https://www.bilibili.com/video/%s", bvid)
+	if err := waitForURL(ctx, videoURL); err != nil {
+		return nil, err
+	}
+	c := newBilibiliCollector(ctx)
+
+	var video *BilibiliVideoInfo
+	var parseErr error
 	c.OnHTML("script", func(e *colly.HTMLElement) {
-		scriptContent := e.Text
-		
-		if strings.Contains(scriptContent, "__INITIAL_STATE__") {
-			// 提取视频统计数据
-			if strings.Contains(scriptContent, "\"stat\":") {
-				// 播放数
-				if re := regexp.MustCompile(`"view":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.ViewCount = count
-						}
-					}
-				}
-				
-				// 点赞数
-				if re := regexp.MustCompile(`"like":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.LikeCount = count
-						}
-					}
-				}
-				
-				// 投币数
-				if re := regexp.MustCompile(`"coin":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.CoinCount = count
-						}
-					}
-				}
-				
-				// 收藏数
-				if re := regexp.MustCompile(`"favorite":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.FavoriteCount = count
-						}
-					}
-				}
-				
-				// 分享数
-				if re := regexp.MustCompile(`"share":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.ShareCount = count
-						}
-					}
-				}
-				
-				// 评论数
-				if re := regexp.MustCompile(`"reply":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if count, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.ReplyCount = count
-						}
-					}
-				}
-			}
+		if video != nil || !strings.Contains(e.Text, "__INITIAL_STATE__") {
+			return
+		}
 
-			// 提取视频基本信息
-			if strings.Contains(scriptContent, "\"videoData\":") {
-				// 标题
-				if re := regexp.MustCompile(`"title":"([^"]+)"`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						video.Title = matches[1]
-					}
-				}
-				
-				// 描述
-				if re := regexp.MustCompile(`"desc":"([^"]*)"`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						video.Description = matches[1]
-					}
-				}
-				
-				// 封面
-				if re := regexp.MustCompile(`"pic":"([^"]+)"`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						video.CoverURL = "https:" + matches[1]
-					}
-				}
-				
-				// 时长
-				if re := regexp.MustCompile(`"duration":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if seconds, err := strconv.Atoi(matches[1]); err == nil {
-							video.Duration = formatDuration(seconds)
-						}
-					}
-				}
-				
-				// UP主
-				if re := regexp.MustCompile(`"owner":\{[^}]*"name":"([^"]+)"`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						video.Author = matches[1]
-					}
-				}
-				
-				// 发布时间
-				if re := regexp.MustCompile(`"pubdate":(\d+)`); re != nil {
-					if matches := re.FindStringSubmatch(scriptContent); len(matches) > 1 {
-						if timestamp, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-							video.UploadDate = time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
-						}
-					}
-				}
-			}
+		var initialState biliVideoInitialState
+		ok, err := extractBilibiliInitialState(e.Text, &initialState)
+		if !ok {
+			return
 		}
+		if err != nil {
+			parseErr = err
+			return
+		}
+		if initialState.VideoData.Bvid == "" {
+			parseErr = errors.New("__INITIAL_STATE__ has no videoData")
+			return
+		}
+		video = initialState.VideoData.toVideoInfo()
 	})
 
 	c.OnError(func(r *colly.Response, err error) {
 		slog.Error("Bilibili video details scraping error", "url", r.Request.URL, "error", err)
 	})
 
-	err := c.Visit(video.VideoURL)
+	err := c.Visit(videoURL)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to visit Bilibili video: %s", video.VideoURL)
+		return nil, errors.Wrapf(err, "failed to visit Bilibili video: %s", videoURL)
+	}
+	if video == nil {
+		if parseErr == nil {
+			parseErr = errors.New("no __INITIAL_STATE__ in page")
+		}
+		return nil, errors.Wrapf(parseErr, "failed to parse Bilibili video: %s", videoURL)
 	}
 
 	return video, nil
@@ -569,77 +642,9 @@ func parseBilibiliCount(countStr string) (int64, error) {
 	return 0, errors.New("failed to parse count string: " + countStr)
 }
 
-// FormatBilibiliUserMessage 格式化Bilibili用户信息为消息
-func FormatBilibiliUserMessage(userInfo *BilibiliUserInfo, videos []BilibiliVideoInfo) []string {
-	var messages []string
-	var message strings.Builder
-
-	// 用户基本信息
-	message.WriteString(fmt.Sprintf("# Bilibili 用户信息\n"))
-	message.WriteString(fmt.Sprintf("**用户名**: %s\n", userInfo.Username))
-	message.WriteString(fmt.Sprintf("**UID**: %s\n", userInfo.UserID))
-	message.WriteString(fmt.Sprintf("**粉丝数**: %s\n", formatCount(userInfo.FollowerCount)))
-	message.WriteString(fmt.Sprintf("**关注数**: %s\n", formatCount(userInfo.FollowingCount)))
-	message.WriteString(fmt.Sprintf("**获赞数**: %s\n", formatCount(userInfo.LikeCount)))
-	message.WriteString(fmt.Sprintf("**播放数**: %s\n", formatCount(userInfo.PlayCount)))
-	message.WriteString(fmt.Sprintf("**等级**: Lv.%d\n", userInfo.Level))
-	if userInfo.VipType > 0 {
-		vipText := "月度大会员"
-		if userInfo.VipType == 2 {
-			vipText = "年度大会员"
-		}
-		message.WriteString(fmt.Sprintf("**会员类型**: %s\n", vipText))
-	}
-	message.WriteString(fmt.Sprintf("**个人空间**: %s\n", userInfo.SpaceURL))
-	if userInfo.Description != "" {
-		message.WriteString(fmt.Sprintf("**简介**: %s\n", userInfo.Description))
-	}
-	message.WriteString("\n")
-
-	messages = append(messages, message.String())
-	message.Reset()
-
-	// 最新视频信息
-	if len(videos) > 0 {
-		message.WriteString("## 最新视频\n")
-		for idx, video := range videos {
-			if idx >= 10 { // 限制显示数量
-				break
-			}
-			message.WriteString(fmt.Sprintf("### %d. [%s](%s)\n", idx+1, video.Title, video.VideoURL))
-			if video.ViewCount > 0 {
-				message.WriteString(fmt.Sprintf("**播放量**: %s\n", formatCount(video.ViewCount)))
-			}
-			if video.LikeCount > 0 {
-				message.WriteString(fmt.Sprintf("**点赞数**: %s\n", formatCount(video.LikeCount)))
-			}
-			if video.CoinCount > 0 {
-				message.WriteString(fmt.Sprintf("**投币数**: %s\n", formatCount(video.CoinCount)))
-			}
-			if video.FavoriteCount > 0 {
-				message.WriteString(fmt.Sprintf("**收藏数**: %s\n", formatCount(video.FavoriteCount)))
-			}
-			if video.UploadDate != "" {
-				message.WriteString(fmt.Sprintf("**发布时间**: %s\n", video.UploadDate))
-			}
-			if video.Duration != "" {
-				message.WriteString(fmt.Sprintf("**时长**: %s\n", video.Duration))
-			}
-			message.WriteString("\n")
-
-			// 每5个视频分一条消息
-			if (idx+1)%5 == 0 {
-				messages = append(messages, message.String())
-				message.Reset()
-			}
-		}
-
-		if left := message.String(); left != "" {
-			messages = append(messages, left)
-		}
-	}
-
-	return messages
+// FormatBilibiliUserMessage 用默认模板将Bilibili用户信息格式化为消息，sections 为每个视频附加的统计
+func FormatBilibiliUserMessage(userInfo *BilibiliUserInfo, videos []BilibiliVideoInfo, sections ...BilibiliUserSection) []string {
+	return renderDefaultMessages(AzutvTaskTypeBilibiliUser, BilibiliUserReport{User: userInfo, Videos: videos, Sections: sections})
 }
 
 // formatCount 格式化数字显示（转换为万、亿等单位）
@@ -652,40 +657,73 @@ func formatCount(count int64) string {
 	return fmt.Sprintf("%d", count)
 }
 
-// SendBilibiliUserInfo 获取并发送Bilibili用户信息到Discord
-func SendBilibiliUserInfo(uid string) error {
+// SendBilibiliUserInfo 获取并发送Bilibili用户信息到Discord，最新视频按 order 排序，
+// sections 为每个视频附加的评论、弹幕统计
+func SendBilibiliUserInfo(ctx context.Context, uid string, order BilibiliVideoOrder, sections []BilibiliUserSection) error {
 	// 获取用户信息
-	userInfo, err := GetBilibiliUserInfo(uid)
+	userInfo, err := GetBilibiliUserInfo(ctx, uid)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get Bilibili user info for %s", uid)
 	}
 
 	// 获取最新视频
-	videos, err := GetBilibiliUserVideos(uid, 10)
+	var videos []BilibiliVideoInfo
+	page, err := GetBilibiliUserVideos(ctx, uid, BilibiliVideoQuery{Page: 1, PageSize: 10, Order: order})
 	if err != nil {
 		slog.Warn("Failed to get Bilibili videos", "uid", uid, "error", err)
 		videos = []BilibiliVideoInfo{} // 继续处理，但没有视频信息
+	} else {
+		videos = page.Videos
+		userInfo.VideoCount = page.Total
+		userInfo.setSource("videos", bilibiliSourceArcSearch)
 	}
 
-	// 获取视频详细信息（包括点赞数、投币数等）
-	for i := range videos {
-		if videoDetails, err := GetBilibiliVideoDetails(videos[i].BvID); err == nil {
-			videos[i].LikeCount = videoDetails.LikeCount
-			videos[i].CoinCount = videoDetails.CoinCount
-			videos[i].FavoriteCount = videoDetails.FavoriteCount
-			videos[i].ShareCount = videoDetails.ShareCount
+	// 并发获取视频详细信息（包括点赞数、投币数等），按主机限速
+	errs := workpool.Run(ctx, len(videos), fetchWorkers, func(ctx context.Context, i int) error {
+		videoDetails, err := GetBilibiliVideoDetails(ctx, videos[i].BvID)
+		if err != nil {
+			return err
+		}
+		videos[i].LikeCount = videoDetails.LikeCount
+		videos[i].CoinCount = videoDetails.CoinCount
+		videos[i].FavoriteCount = videoDetails.FavoriteCount
+		videos[i].ShareCount = videoDetails.ShareCount
+		if videoDetails.ReplyCount > 0 {
 			videos[i].ReplyCount = videoDetails.ReplyCount
-			if videos[i].Author == "" {
-				videos[i].Author = videoDetails.Author
-			}
 		}
-		// 添加延迟避免被限制
-		time.Sleep(100 * time.Millisecond)
+		if videos[i].Author == "" {
+			videos[i].Author = videoDetails.Author
+		}
+		videos[i].Parts = videoDetails.Parts
+		videos[i].CID = videoDetails.CID
+		videos[i].DanmakuCount = videoDetails.DanmakuCount
+		videos[i].cidSeconds = videoDetails.cidSeconds
+		// 附加统计失败时只缺少对应部分，不影响视频本身
+		if err := getBilibiliVideoSections(ctx, &videos[i], sections); err != nil {
+			slog.Warn("Failed to get Bilibili video statistics", "bvid", videos[i].BvID, "error", err)
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			slog.Warn("Failed to get Bilibili video details", "bvid", videos[i].BvID, "error", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	// 格式化消息
-	messages := FormatBilibiliUserMessage(userInfo, videos)
+	items := make([]history.Item, 0, len(videos))
+	for idx, video := range videos {
+		items = append(items, newHistoryItem(video.BvID, idx+1, video.Title, video.VideoURL, userInfo.Username, video))
+	}
 
-	// 发送到Discord
-	return SendMessageToDiscord(messages, config.GetDiscordChatWebhookUrl(), ServiceNameBilibiliUser)
+	// 记录历史并发送到Discord
+	return publish(publication{
+		Task:     AzutvTaskTypeBilibiliUser,
+		Variant:  uid,
+		Username: ServiceNameBilibiliUser,
+		Items:    items,
+		Data:     BilibiliUserReport{User: userInfo, Videos: videos, Sections: sections},
+	})
 }
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+// BilibiliUserSection bilibili_user 报告中每个视频可附加的统计
+type BilibiliUserSection string
+
+const (
+	BilibiliUserSectionComments BilibiliUserSection = "comments" // 点赞最多的评论
+	BilibiliUserSectionDanmaku  BilibiliUserSection = "danmaku"  // 弹幕总数和时间分布
+)
+
+const (
+	// bilibiliTopCommentCount 每个视频列出的热门评论数
+	bilibiliTopCommentCount = 3
+	// bilibiliDanmakuBuckets 弹幕分布将视频时间轴均分的段数
+	bilibiliDanmakuBuckets = 10
+	// bilibiliDanmakuBarWidth 弹幕最多的一段在分布图中的长度
+	bilibiliDanmakuBarWidth = 16
+)
+
+// ParseBilibiliUserSections 解析附加统计名称列表（comments、danmaku），重复的名称只保留一个
+func ParseBilibiliUserSections(sections []string) ([]BilibiliUserSection, error) {
+	var parsed []BilibiliUserSection
+	for _, section := range sections {
+		switch s := BilibiliUserSection(strings.ToLower(strings.TrimSpace(section))); s {
+		case BilibiliUserSectionComments, BilibiliUserSectionDanmaku:
+			if !slices.Contains(parsed, s) {
+				parsed = append(parsed, s)
+			}
+		default:
+			return nil, errors.Errorf("unknown Bilibili user section %q, expected comments or danmaku", section)
+		}
+	}
+	return parsed, nil
+}
+
+// BilibiliComment 视频的一条评论
+type BilibiliComment struct {
+	Author    string    `json:"author"`
+	Message   string    `json:"message"`
+	Likes     int64     `json:"likes"`
+	Replies   int64     `json:"replies"` // 楼中楼回复数
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BilibiliDanmakuBucket 弹幕分布中的一段时间
+type BilibiliDanmakuBucket struct {
+	Start string `json:"start"` // 该段的起始时间，如 1:30
+	Count int    `json:"count"`
+	Bar   string `json:"bar"` // 按弹幕最多的一段缩放的条形
+}
+
+// GetBilibiliTopComments 获取视频点赞最多的 n 条评论（不含置顶评论）
+func GetBilibiliTopComments(ctx context.Context, aid string, n int) ([]BilibiliComment, error) {
+	params := url.Values{}
+	params.Set("type", "1") // 1 为视频评论区
+	params.Set("oid", aid)
+	params.Set("sort", "1") // 1 为按点赞数排序
+	params.Set("pn", "1")
+	params.Set("ps", strconv.Itoa(n))
+
+	var data struct {
+		Replies []struct {
+			Like   int64 `json:"like"`
+			Rcount int64 `json:"rcount"`
+			Ctime  int64 `json:"ctime"`
+			Member struct {
+				Uname string `json:"uname"`
+			} `json:"member"`
+			Content struct {
+				Message string `json:"message"`
+			} `json:"content"`
+		} `json:"replies"`
+	}
+	referer := fmt.Sprintf("https://www.bilibili.com/video/av%s", aid)
+	if err := callBilibiliAPI(ctx, "/x/v2/reply", params, false, referer, &data); err != nil {
+		return nil, errors.Wrapf(err, "failed to get comments of av%s", aid)
+	}
+
+	comments := make([]BilibiliComment, 0, len(data.Replies))
+	for _, r := range data.Replies {
+		comments = append(comments, BilibiliComment{
+			Author: r.Member.Uname,
+			// 评论中的换行会打断消息中的引用格式
+			Message:   strings.Join(strings.Fields(r.Content.Message), " "),
+			Likes:     r.Like,
+			Replies:   r.Rcount,
+			CreatedAt: time.Unix(r.Ctime, 0),
+		})
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Likes > comments[j].Likes
+	})
+	if len(comments) > n {
+		comments = comments[:n]
+	}
+	return comments, nil
+}
+
+// bilibiliDanmakuXML comment.bilibili.com 返回的弹幕文件，p 属性的第一项为出现时间（秒）
+type bilibiliDanmakuXML struct {
+	Items []struct {
+		P string `xml:"p,attr"`
+	} `xml:"d"`
+}
+
+// GetBilibiliDanmakuTimes 获取分P弹幕池中每条弹幕的出现时间（秒）；
+// 弹幕池有上限（maxlimit），热门视频只能取到最近的一部分弹幕
+func GetBilibiliDanmakuTimes(ctx context.Context, cid int64) ([]float64, error) {
+	danmakuURL := fmt.Sprintf("https://comment.bilibili.com/%d.xml", cid)
+	if err := waitForURL(ctx, danmakuURL); err != nil {
+		return nil, err
+	}
+	resp, err := resty.New().R().
+		SetContext(ctx).
+		SetHeader("User-Agent", bilibiliUserAgent).
+		Get(danmakuURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request %s", danmakuURL)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("error status code %d for %s", resp.StatusCode(), danmakuURL)
+	}
+	times, err := parseBilibiliDanmakuXML(resp.Body(), resp.Header().Get("Content-Encoding"))
+	return times, errors.Wrapf(err, "failed to parse %s", danmakuURL)
+}
+
+// parseBilibiliDanmakuXML 解析弹幕文件，返回每条弹幕的出现时间（秒），无法解析时间的弹幕被忽略
+func parseBilibiliDanmakuXML(body []byte, contentEncoding string) ([]float64, error) {
+	// 弹幕文件以 raw deflate 压缩，net/http 只会自动解压 gzip
+	if contentEncoding == "deflate" {
+		inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(body)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to inflate danmaku")
+		}
+		body = inflated
+	}
+
+	var doc bilibiliDanmakuXML
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode danmaku")
+	}
+	times := make([]float64, 0, len(doc.Items))
+	for _, item := range doc.Items {
+		first, _, _ := strings.Cut(item.P, ",")
+		t, err := strconv.ParseFloat(first, 64)
+		// ParseFloat 接受 "NaN"、"Inf"，它们无法落入任何一段
+		if err != nil || t < 0 || math.IsNaN(t) || math.IsInf(t, 0) {
+			continue
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// buildBilibiliDanmakuHistogram 将弹幕时间按 duration 秒均分为 buckets 段统计，
+// duration 未知时以最后一条弹幕的时间为准
+func buildBilibiliDanmakuHistogram(times []float64, duration int, buckets int) []BilibiliDanmakuBucket {
+	if len(times) == 0 || buckets <= 0 {
+		return nil
+	}
+	span := float64(duration)
+	if span <= 0 {
+		for _, t := range times {
+			span = max(span, t)
+		}
+	}
+	span = max(span, 1)
+
+	counts := make([]int, buckets)
+	for _, t := range times {
+		idx := int(t / span * float64(buckets))
+		// 片尾之后的弹幕计入最后一段，负数时间计入第一段
+		counts[min(max(idx, 0), buckets-1)]++
+	}
+
+	peak := 0
+	for _, c := range counts {
+		peak = max(peak, c)
+	}
+	histogram := make([]BilibiliDanmakuBucket, buckets)
+	for i, c := range counts {
+		width := c * bilibiliDanmakuBarWidth / peak
+		if c > 0 && width == 0 {
+			width = 1
+		}
+		histogram[i] = BilibiliDanmakuBucket{
+			Start: formatDuration(int(span * float64(i) / float64(buckets))),
+			Count: c,
+			Bar:   strings.Repeat("█", width),
+		}
+	}
+	return histogram
+}
+
+// getBilibiliVideoSections 按 sections 为视频获取热门评论和弹幕分布，视频的 AvID、CID 需已填写
+func getBilibiliVideoSections(ctx context.Context, video *BilibiliVideoInfo, sections []BilibiliUserSection) error {
+	var errs []string
+	for _, section := range sections {
+		switch section {
+		case BilibiliUserSectionComments:
+			if video.AvID == "" || video.AvID == "0" {
+				errs = append(errs, "no aid for comments")
+				continue
+			}
+			comments, err := GetBilibiliTopComments(ctx, video.AvID, bilibiliTopCommentCount)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			video.TopComments = comments
+
+		case BilibiliUserSectionDanmaku:
+			if video.CID == 0 {
+				errs = append(errs, "no cid for danmaku")
+				continue
+			}
+			times, err := GetBilibiliDanmakuTimes(ctx, video.CID)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			video.DanmakuSampled = len(times)
+			video.DanmakuHistogram = buildBilibiliDanmakuHistogram(times, video.cidSeconds, bilibiliDanmakuBuckets)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildBilibiliDanmakuHistogram(t *testing.T) {
+	tests := []struct {
+		name      string
+		times     []float64
+		duration  int
+		buckets   int
+		wantCount []int
+		wantStart []string
+	}{
+		{
+			name:      "even split",
+			times:     []float64{0, 1, 24.9, 25, 50, 99.9},
+			duration:  100,
+			buckets:   4,
+			wantCount: []int{3, 1, 1, 1},
+			wantStart: []string{"0:00", "0:25", "0:50", "1:15"},
+		},
+		{
+			// 片尾之后的弹幕（如最后一秒的四舍五入、分P时长不准）计入最后一段
+			name:      "times past the end",
+			times:     []float64{10, 100, 150},
+			duration:  100,
+			buckets:   2,
+			wantCount: []int{1, 2},
+			wantStart: []string{"0:00", "0:50"},
+		},
+		{
+			name:      "unknown duration uses the last danmaku",
+			times:     []float64{0, 30, 60},
+			duration:  0,
+			buckets:   2,
+			wantCount: []int{1, 2},
+			wantStart: []string{"0:00", "0:30"},
+		},
+		{
+			// 时长未知且弹幕都在第 0 秒时跨度为 0，按 1 秒处理
+			name:      "zero span",
+			times:     []float64{0, 0},
+			duration:  0,
+			buckets:   3,
+			wantCount: []int{2, 0, 0},
+			wantStart: []string{"0:00", "0:00", "0:00"},
+		},
+		{
+			// 解析时已过滤负数时间，直接传入时也不能越界
+			name:      "negative time",
+			times:     []float64{-5, 10},
+			duration:  20,
+			buckets:   2,
+			wantCount: []int{1, 1},
+			wantStart: []string{"0:00", "0:10"},
+		},
+		{
+			name:     "no danmaku",
+			duration: 100,
+			buckets:  10,
+		},
+		{
+			name:     "no buckets",
+			times:    []float64{1},
+			duration: 100,
+			buckets:  0,
+		},
+	}
+	for _, tt := range tests {
+		histogram := buildBilibiliDanmakuHistogram(tt.times, tt.duration, tt.buckets)
+		var counts []int
+		var starts []string
+		for _, bucket := range histogram {
+			counts = append(counts, bucket.Count)
+			starts = append(starts, bucket.Start)
+		}
+		if !reflect.DeepEqual(counts, tt.wantCount) || !reflect.DeepEqual(starts, tt.wantStart) {
+			t.Errorf("%s: counts = %v, starts = %v; want %v, %v", tt.name, counts, starts, tt.wantCount, tt.wantStart)
+		}
+	}
+}
+
+func TestBilibiliDanmakuHistogramBars(t *testing.T) {
+	// 最多的一段占满宽度，很少但不为 0 的段至少有一格
+	times := make([]float64, 0, 101)
+	for range 100 {
+		times = append(times, 1)
+	}
+	times = append(times, 99)
+	histogram := buildBilibiliDanmakuHistogram(times, 100, 2)
+
+	wantWidths := []int{bilibiliDanmakuBarWidth, 1}
+	for i, bucket := range histogram {
+		if got := strings.Count(bucket.Bar, "█"); got != wantWidths[i] {
+			t.Errorf("bucket %d: bar width = %d, want %d", i, got, wantWidths[i])
+		}
+	}
+}
+
+func TestParseBilibiliDanmakuXML(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?><i><chatserver>chat.bilibili.com</chatserver><chatid>111</chatid><maxlimit>1000</maxlimit>` +
+		`<d p="12.345,1,25,16777215,1700000000,0,abcdef,1,10">第一条</d>` +
+		`<d p="3,5,25,16777215,1700000001,0,abcdef,2,10">第二条</d>` +
+		`<d p="bad,1,25">无效</d>` +
+		`<d p="-1,1,25">无效</d>` +
+		`<d p="NaN,1,25">无效</d>` +
+		`<d p="+Inf,1,25">无效</d>` +
+		`<d p="-Inf,1,25">无效</d></i>`
+	want := []float64{12.345, 3}
+
+	var deflated bytes.Buffer
+	w, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(doc))
+	w.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		wantErr  bool
+	}{
+		{"plain", []byte(doc), "", false},
+		{"deflate", deflated.Bytes(), "deflate", false},
+		{"deflate header on plain body", []byte(doc), "deflate", true},
+		{"not XML", []byte("<html"), "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseBilibiliDanmakuXML(tt.body, tt.encoding)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestBilibiliVideoDataDanmakuFields(t *testing.T) {
+	var data biliVideoData
+	data.Bvid = "BV1xx411c7mD"
+	data.Stat.Danmaku = 50
+	data.Pages = []biliVideoPage{{Cid: 111, Page: 1, Duration: 240}, {Cid: 222, Page: 2, Duration: 60}}
+
+	video := data.toVideoInfo()
+	if video.DanmakuCount != 50 || video.CID != 111 || video.cidSeconds != 240 {
+		t.Errorf("DanmakuCount = %d, CID = %d, cidSeconds = %d; want 50, 111, 240", video.DanmakuCount, video.CID, video.cidSeconds)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"azuserver/lib/workpool"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gocolly/colly"
+)
+
+// fetchWorkers 单个任务中同时抓取的最大数量
+const fetchWorkers = 8
+
+// fetchLimiter 所有任务共享的按主机限速器，每个站点每秒最多 5 个请求
+var fetchLimiter = workpool.NewLimiter(5, 5)
+
+// waitForURL 按请求地址的主机等待限速器放行
+func waitForURL(ctx context.Context, rawURL string) error {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return fetchLimiter.Wait(ctx, host)
+}
+
+// contextTransport 将请求绑定到 ctx，ctx 取消时进行中的请求随之中止
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// bindCollector 将 colly 采集器的请求绑定到 ctx，colly 本身不支持 context
+func bindCollector(ctx context.Context, c *colly.Collector) {
+	c.WithTransport(contextTransport{ctx: ctx, base: http.DefaultTransport})
+}
+
+// extractScriptJSON 从页面脚本中截取 markers 中第一个出现的赋值语句右侧的 JSON 对象并解码到 v，
+// 脚本中没有任何 marker 时返回 false
+func extractScriptJSON(script string, markers []string, v any) (bool, error) {
+	start := -1
+	for _, marker := range markers {
+		if idx := strings.Index(script, marker); idx >= 0 {
+			start = idx + len(marker)
+			break
+		}
+	}
+	if start < 0 {
+		return false, nil
+	}
+
+	// json.Decoder 只读取一个完整的 JSON 值，忽略其后的 ";" 和其他脚本
+	decoder := json.NewDecoder(strings.NewReader(script[start:]))
+	return true, decoder.Decode(v)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractScriptJSON(t *testing.T) {
+	markers := []string{"window.__DATA__=", "window.__DATA__ = "}
+	tests := []struct {
+		name      string
+		script    string
+		wantFound bool
+		wantErr   bool
+		want      map[string]any
+	}{
+		{
+			name:      "trailing script ignored",
+			script:    `window.__DATA__={"a":1,"b":"x;y"};(function(){var c={"a":2}})()`,
+			wantFound: true,
+			want:      map[string]any{"a": float64(1), "b": "x;y"},
+		},
+		{
+			name:      "second marker",
+			script:    `var x=1; window.__DATA__ = {"a":3}; window.other={}`,
+			wantFound: true,
+			want:      map[string]any{"a": float64(3)},
+		},
+		{
+			name:      "nested braces in strings",
+			script:    `window.__DATA__={"a":"}{","b":{"c":[1,{"d":"}"}]}}`,
+			wantFound: true,
+			want:      map[string]any{"a": "}{", "b": map[string]any{"c": []any{float64(1), map[string]any{"d": "}"}}}},
+		},
+		{
+			name:   "no marker",
+			script: `window.__OTHER__={"a":1}`,
+		},
+		{
+			name:      "truncated JSON",
+			script:    `window.__DATA__={"a":`,
+			wantFound: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		var got map[string]any
+		found, err := extractScriptJSON(tt.script, markers, &got)
+		if found != tt.wantFound || (err != nil) != tt.wantErr {
+			t.Errorf("%s: found = %v, err = %v; want found = %v, err = %v", tt.name, found, err, tt.wantFound, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
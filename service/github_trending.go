@@ -1,9 +1,9 @@
 package service
 
 import (
-	"azuserver/config"
-	"fmt"
+	"azuserver/lib/history"
 	"log/slog"
+	"net/url"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -11,17 +11,23 @@ import (
 	"github.com/pkg/errors"
 )
 
-func SendGithubTrending() {
-	githubTrendingMessages, err := GetGithubTrendingMessage()
+func SendGithubTrending(language string, since string) {
+	entries, err := FetchGithubTrending(language, since)
 	if err != nil {
 		slog.Warn(errors.Wrapf(err, "failed to get Github Trending").Error())
 		return
 	}
-	if err := SendMessageToDiscord(
-		githubTrendingMessages,
-		config.GetDiscordChatWebhookUrl(),
-		ServiceNameGithubTrending,
-	); err != nil {
+	items := make([]history.Item, 0, len(entries))
+	for idx, entry := range entries {
+		items = append(items, newHistoryItem(entry.Title, idx+1, entry.Title, entry.Link, entry.Description, entry))
+	}
+	if err := publish(publication{
+		Task:     AzutvTaskTypeGithubTrending,
+		Variant:  GithubTrendingVariant(language, since),
+		Username: ServiceNameGithubTrending,
+		Items:    items,
+		Data:     entries,
+	}); err != nil {
 		slog.Warn(errors.Wrapf(err, "failed to send Github Trending to Discord").Error())
 		return
 	}
@@ -29,6 +35,11 @@ func SendGithubTrending() {
 
 const ServiceNameGithubTrending = "Github Trending"
 
+// GithubTrendingVariant 返回语言和时间范围组成的变体名，如 "go weekly"，用于路由和历史记录
+func GithubTrendingVariant(language string, since string) string {
+	return strings.TrimSpace(language + " " + since)
+}
+
 type GithubTrendingEntry struct {
 	Title       string
 	Link        string
@@ -38,6 +49,15 @@ type GithubTrendingEntry struct {
 }
 
 func GetGithubTrendingMessage() ([]string, error) {
+	entries, err := FetchGithubTrending("", "")
+	if err != nil {
+		return nil, err
+	}
+	return FormatGithubTrendingMessages(entries), nil
+}
+
+// FetchGithubTrending 获取 Github Trending，language 为空时不限语言，since 可为 daily、weekly、monthly
+func FetchGithubTrending(language string, since string) ([]GithubTrendingEntry, error) {
 	entries := []GithubTrendingEntry{}
 	c := colly.NewCollector()
 
@@ -62,30 +82,21 @@ func GetGithubTrendingMessage() ([]string, error) {
 		})
 	})
 
-	err := c.Visit("https://github.com/trending")
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to visit Github trending")
+	trendingURL := "https://github.com/trending"
+	if language != "" {
+		trendingURL += "/" + url.PathEscape(strings.ToLower(language))
 	}
-
-	var message strings.Builder
-	var messages []string
-	for idx, entry := range entries {
-		if entry.Language != "" {
-			entry.Language = fmt.Sprintf("**%s** - ", entry.Language)
-		}
-		message.WriteString(fmt.Sprintf("## \\#%d  [%s](<%s>)\n%s⭐ %s\n",
-			idx+1, entry.Title, entry.Link, entry.Language, entry.Stars))
-		message.WriteString(entry.Description + "\n")
-		// TODO: split by size.
-		if (idx+1)%5 == 0 {
-			messages = append(messages, message.String())
-			message.Reset()
-		}
+	if since != "" {
+		trendingURL += "?since=" + url.QueryEscape(since)
 	}
-
-	if left := message.String(); left != "" {
-		messages = append(messages, left)
+	err := c.Visit(trendingURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to visit Github trending")
 	}
+	return entries, nil
+}
 
-	return messages, nil
+// FormatGithubTrendingMessages 用默认模板渲染 Github Trending 消息
+func FormatGithubTrendingMessages(entries []GithubTrendingEntry) []string {
+	return renderDefaultMessages(AzutvTaskTypeGithubTrending, entries)
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"azuserver/config"
+	"azuserver/lib/history"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -13,16 +14,17 @@ import (
 
 // Oricon Ranking.
 func SendOriconRanking() {
-	oriconRankMessage, err := GetOriconRankingDataMessage()
+	rankData, err := FetchRankingDataFromOricon()
 	if err != nil {
-		slog.Warn(err.Error())
+		slog.Warn(errors.Wrapf(err, "failed to get ranking data from Oricon").Error())
 		return
 	}
-	if err := SendMessageToDiscord(
-		[]string{oriconRankMessage},
-		config.GetDiscordChatWebhookUrl(),
-		ServiceNameOriconRanking,
-	); err != nil {
+	if err := publish(publication{
+		Task:     AzutvTaskTypeOriconRanking,
+		Username: ServiceNameOriconRanking,
+		Items:    rankData.HistoryItems(),
+		Data:     rankData,
+	}); err != nil {
 		slog.Warn(errors.Wrapf(err, "failed to send Oricon Ranking to Discord").Error())
 		return
 	}
@@ -155,22 +157,34 @@ func FetchRankingDataFromOricon() (OriconRankingDataArray, error) {
 	}, retErr
 }
 
+// Dump 用默认模板渲染 Oricon 排行
 func (oriconRankData OriconRankingDataArray) Dump() string {
-	var oriconRank strings.Builder
+	return strings.Join(renderDefaultMessages(AzutvTaskTypeOriconRanking, oriconRankData), "")
+}
+
+// HistoryItems 将各榜单条目展开为历史条目，同一作品在多个榜单中只记录一次
+func (oriconRankData OriconRankingDataArray) HistoryItems() []history.Item {
+	var items []history.Item
+	seen := make(map[string]bool)
 	for _, data := range oriconRankData {
-		oriconRank.WriteString(fmt.Sprintf("### %s\n", data.Rule))
-		for _, entry := range data.Entries {
-			if entry.Link == "" {
-				oriconRank.WriteString(fmt.Sprintf("%s - %s ", entry.Title, entry.Artist))
-			} else {
-				oriconRank.WriteString(fmt.Sprintf("[%s](<https://%s/%s>) - %s ", entry.Title, config.DomainOricon, entry.Link, entry.Artist))
+		for idx, entry := range data.Entries {
+			id := entry.Link
+			if id == "" {
+				id = entry.Title + "/" + entry.Artist
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			link := ""
+			if entry.Link != "" {
+				link = fmt.Sprintf("https://%s/%s", config.DomainOricon, strings.TrimPrefix(entry.Link, "/"))
 			}
-			oriconRank.WriteString(oriconRankingTrendToEmoji(entry.Trend))
-			oriconRank.WriteRune('\n')
+			items = append(items, newHistoryItem(id, idx+1, entry.Title+" - "+entry.Artist, link, data.Rule, entry))
 		}
-		oriconRank.WriteRune('\n')
 	}
-	return oriconRank.String()
+	return items
 }
 
 func GetOriconRankingDataMessage() (string, error) {
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/feed"
+	"azuserver/lib/history"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// OutputMode 任务结果的输出方式
+type OutputMode string
+
+const (
+	// OutputModeDiscord 发送到 Discord 并记录历史（默认）
+	OutputModeDiscord OutputMode = "discord"
+	// OutputModeStdout 打印每条消息及其长度，不发送也不记录
+	OutputModeStdout OutputMode = "stdout"
+	// OutputModeJSON 打印类型化的任务数据
+	OutputModeJSON OutputMode = "json"
+	// OutputModeMarkdown 打印拼接后的消息正文
+	OutputModeMarkdown OutputMode = "markdown"
+)
+
+// DiscordMessageLimit Discord 单条消息的最大字符数
+const DiscordMessageLimit = 2000
+
+var outputMode = OutputModeDiscord
+
+// SetOutputMode 设置任务结果的输出方式
+func SetOutputMode(mode string) error {
+	switch m := OutputMode(mode); m {
+	case OutputModeDiscord, OutputModeStdout, OutputModeJSON, OutputModeMarkdown:
+		outputMode = m
+		return nil
+	default:
+		return errors.Errorf("invalid output mode %q", mode)
+	}
+}
+
+// IsDryRun 是否只输出结果而不发送、不修改本地状态
+func IsDryRun() bool {
+	return outputMode != OutputModeDiscord
+}
+
+// publication 一次任务运行的全部输出
+type publication struct {
+	Task     AzutvTaskType
+	Variant  string
+	Username string
+	Items    []history.Item
+	// Data 任务的类型化数据，按任务和目标对应的模板渲染为消息
+	Data any
+}
+
+// newHistoryItem 构造历史条目，data 为任务自身的类型化条目
+func newHistoryItem(id string, rank int, title string, link string, summary string, data any) history.Item {
+	item := history.Item{
+		ID:      id,
+		Rank:    rank,
+		Title:   title,
+		Link:    link,
+		Summary: summary,
+	}
+	if raw, err := json.Marshal(data); err == nil {
+		item.Data = raw
+	}
+	return item
+}
+
+// publish 发送消息，至少一个目标发送成功后记录历史、更新订阅；试运行时只打印结果。
+// 全部发送失败时不记录并返回错误，重试时条目在订阅中仍是新的；部分失败只记录日志并返回 nil，
+// 否则调用方不保存状态，下次运行会向已成功的目标重复发送
+func publish(p publication) error {
+	if IsDryRun() {
+		return printPublication(os.Stdout, p)
+	}
+
+	destinations, err := resolveDestinations(p)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, d := range destinations {
+		messages, err := renderMessages(p.Task, d.Name, p.Data)
+		if err == nil {
+			err = SendMessageToDiscord(messages, d.Webhook)
+		}
+		if err != nil {
+			failed++
+			slog.Warn(errors.Wrapf(err, "failed to deliver %s to %s", p.Task, d.Name).Error())
+		}
+	}
+	if failed == len(destinations) {
+		return errors.Errorf("failed to deliver %s to any of %d destinations", p.Task, failed)
+	}
+	if failed > 0 {
+		slog.Warn(fmt.Sprintf("delivered %s to %d of %d destinations", p.Task, len(destinations)-failed, len(destinations)))
+	}
+	if err := recordRun(p); err != nil {
+		slog.Warn(errors.Wrapf(err, "failed to record %s", p.Task).Error())
+	}
+	return nil
+}
+
+// destination 一次发送的目标
+type destination struct {
+	// Name 用于日志和模板选择的目标名，未命名的 webhook 为 "webhook"，默认频道为 "default"
+	Name    string
+	Webhook DiscordWebhook
+}
+
+// resolveDestinations 按 routes 配置解析发送目标，未配置路由时发送到 chat_webhook；
+// 配置了路由但没有一个有效时返回错误，而不是静默地不发送
+func resolveDestinations(p publication) ([]destination, error) {
+	routes := config.GetRoutes(string(p.Task), p.Variant)
+	if len(routes) == 0 {
+		return []destination{{
+			Name: "default",
+			Webhook: DiscordWebhook{
+				Url:      config.GetDiscordChatWebhookUrl(),
+				Username: p.Username,
+			},
+		}}, nil
+	}
+
+	var destinations []destination
+	for _, route := range routes {
+		d := destination{
+			Name: "webhook",
+			Webhook: DiscordWebhook{
+				Url:      route.Webhook,
+				Username: p.Username,
+			},
+		}
+		if route.Sink != "" && route.Webhook != "" {
+			slog.Warn(fmt.Sprintf("route for %s sets both sink %q and webhook, use one of them", p.Task, route.Sink))
+			continue
+		}
+		if route.Sink != "" {
+			sink, ok := config.GetSink(route.Sink)
+			if !ok {
+				slog.Warn(fmt.Sprintf("unknown sink %q in routes for %s", route.Sink, p.Task))
+				continue
+			}
+			d.Name = route.Sink
+			d.Webhook.Url = sink.Webhook
+			if sink.Username != "" {
+				d.Webhook.Username = sink.Username
+			}
+			d.Webhook.AvatarUrl = sink.AvatarURL
+		}
+		if route.Username != "" {
+			d.Webhook.Username = route.Username
+		}
+		if route.AvatarURL != "" {
+			d.Webhook.AvatarUrl = route.AvatarURL
+		}
+		if d.Webhook.Url == "" {
+			slog.Warn(fmt.Sprintf("route %q for %s has no webhook", d.Name, p.Task))
+			continue
+		}
+		destinations = append(destinations, d)
+	}
+	if len(destinations) == 0 {
+		return nil, errors.Errorf("none of the %d routes for %s is valid", len(routes), history.Key(string(p.Task), p.Variant))
+	}
+	return destinations, nil
+}
+
+func recordRun(p publication) error {
+	store := history.NewStore(config.GetDataDir())
+	runs, err := store.Append(history.Run{
+		Task:     string(p.Task),
+		Variant:  p.Variant,
+		Time:     time.Now(),
+		Items:    p.Items,
+		Messages: renderDefaultMessages(p.Task, p.Data),
+	})
+	if err != nil {
+		return err
+	}
+
+	feedDir := config.GetFeedDir()
+	if feedDir == "" {
+		return nil
+	}
+	title := p.Username
+	if p.Variant != "" {
+		title += " - " + p.Variant
+	}
+	key := history.Key(string(p.Task), p.Variant)
+	f := feed.Build(key, runs, feed.Options{
+		Title:   title,
+		BaseURL: config.GetFeedBaseURL(),
+		Mode:    feed.Mode(config.GetFeedMode(string(p.Task))),
+	})
+	return feed.Write(feedDir, key, f)
+}
+
+func printPublication(w io.Writer, p publication) error {
+	switch outputMode {
+	case OutputModeJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(struct {
+			Task    AzutvTaskType `json:"task"`
+			Variant string        `json:"variant,omitempty"`
+			Data    any           `json:"data"`
+		}{p.Task, p.Variant, p.Data})
+
+	case OutputModeMarkdown:
+		_, err := fmt.Fprintln(w, strings.Join(renderDefaultMessages(p.Task, p.Data), "\n"))
+		return err
+
+	default:
+		destinations, err := resolveDestinations(p)
+		if err != nil {
+			return err
+		}
+		for _, d := range destinations {
+			messages, err := renderMessages(p.Task, d.Name, p.Data)
+			if err != nil {
+				return err
+			}
+			for idx, m := range messages {
+				length := utf8.RuneCountInString(m)
+				note := ""
+				if length > DiscordMessageLimit {
+					note = fmt.Sprintf(", exceeds Discord limit of %d", DiscordMessageLimit)
+				}
+				if _, err := fmt.Fprintf(w, "----- %s -> %s [%d/%d] username=%q length=%d%s -----\n%s\n",
+					p.Task, d.Name, idx+1, len(messages), d.Webhook.Username, length, note, m); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"azuserver/config"
+	"azuserver/utils"
+	"context"
 	"fmt"
 	"log/slog"
 
@@ -17,28 +19,59 @@ const (
 	AzutvTaskTypeVocaloidRanking AzutvTaskType = "vocaloid_ranking"
 	AzutvTaskTypeYouTubeUser     AzutvTaskType = "youtube_user"
 	AzutvTaskTypeBilibiliUser    AzutvTaskType = "bilibili_user"
+	AzutvTaskTypeYouTubeWatch    AzutvTaskType = "youtube_watch"
+	AzutvTaskTypeYouTubeLive     AzutvTaskType = "youtube_live"
+	AzutvTaskTypeYouTubePlaylist AzutvTaskType = "youtube_playlist"
+	AzutvTaskTypeBilibiliLive    AzutvTaskType = "bilibili_live"
+	AzutvTaskTypeBilibiliDynamic AzutvTaskType = "bilibili_dynamic"
+	AzutvTaskTypeBilibiliRanking AzutvTaskType = "bilibili_ranking"
 )
 
-func SendMessageToDiscord(messages []string, channelUrl string, username string) error {
+// DiscordWebhook Discord webhook 及发送时使用的用户名、头像
+type DiscordWebhook struct {
+	Url       string
+	Username  string
+	AvatarUrl string
+}
+
+func SendMessageToDiscord(messages []string, webhook DiscordWebhook) error {
 	for _, m := range messages {
 		dcMessage := discordwebhook.Message{
-			Username: &username,
+			Username: &webhook.Username,
 			Content:  &m,
 		}
-		if err := discordwebhook.SendMessage(channelUrl, dcMessage); err != nil {
+		if webhook.AvatarUrl != "" {
+			dcMessage.AvatarUrl = &webhook.AvatarUrl
+		}
+		if err := discordwebhook.SendMessage(webhook.Url, dcMessage); err != nil {
 			return errors.Wrapf(err, "failed to send message to Discord")
 		}
 	}
 	return nil
 }
 
-func RunService(task *string) {
+// ServiceNameSystem 系统通知使用的用户名
+const ServiceNameSystem = "azuserver"
+
+// notifySystem 向系统 webhook 发送运维通知（如登录失效），试运行或未配置系统 webhook 时只记录日志
+func notifySystem(message string) {
+	slog.Warn("System notice", "message", message)
+	webhookURL := config.GetDiscordSysWebhookUrl()
+	if IsDryRun() || webhookURL == "" {
+		return
+	}
+	if err := SendMessageToDiscord([]string{message}, DiscordWebhook{Url: webhookURL, Username: ServiceNameSystem}); err != nil {
+		slog.Error("Failed to send system notice", "error", err)
+	}
+}
+
+func RunService(ctx context.Context, task *string) {
 	switch AzutvTaskType(*task) {
 	case AzutvTaskTypeOriconRanking:
 		SendOriconRanking()
 
 	case AzutvTaskTypeGithubTrending:
-		SendGithubTrending()
+		SendGithubTrending("", "")
 
 	case AzutvTaskTypeVocaloidRanking:
 		SendVocaloidRanking()
@@ -49,7 +82,12 @@ func RunService(task *string) {
 			slog.Error("YouTube default user ID not configured")
 			return
 		}
-		if err := SendYouTubeUserInfo(userID); err != nil {
+		kinds, err := ParseYouTubeVideoKinds(config.GetYouTubeUserSections())
+		if err != nil {
+			slog.Error("Invalid YouTube user sections", "error", err)
+			return
+		}
+		if err := SendYouTubeUserInfo(ctx, userID, kinds); err != nil {
 			slog.Error("Failed to send YouTube user info", "error", err)
 		}
 
@@ -59,10 +97,50 @@ func RunService(task *string) {
 			slog.Error("Bilibili default UID not configured")
 			return
 		}
-		if err := SendBilibiliUserInfo(uid); err != nil {
+		order, err := ParseBilibiliVideoOrder(config.GetBilibiliVideoOrder())
+		if err != nil {
+			slog.Error("Invalid Bilibili video order", "error", err)
+			return
+		}
+		sections, err := ParseBilibiliUserSections(config.GetBilibiliUserSections())
+		if err != nil {
+			slog.Error("Invalid Bilibili user sections", "error", err)
+			return
+		}
+		if err := SendBilibiliUserInfo(ctx, uid, order, sections); err != nil {
 			slog.Error("Failed to send Bilibili user info", "error", err)
 		}
 
+	case AzutvTaskTypeYouTubeWatch:
+		if err := SendYouTubeWatch(ctx, config.GetYouTubeWatchChannels()); err != nil {
+			slog.Error("Failed to send YouTube uploads", "error", err)
+		}
+
+	case AzutvTaskTypeYouTubeLive:
+		if err := SendYouTubeLive(ctx, config.GetYouTubeLiveChannels()); err != nil {
+			slog.Error("Failed to send YouTube live streams", "error", err)
+		}
+
+	case AzutvTaskTypeYouTubePlaylist:
+		if err := SendYouTubePlaylist(ctx, config.GetYouTubePlaylists()); err != nil {
+			slog.Error("Failed to send YouTube playlist changes", "error", err)
+		}
+
+	case AzutvTaskTypeBilibiliLive:
+		if err := SendBilibiliLive(ctx, config.GetBilibiliLiveUIDs()); err != nil {
+			slog.Error("Failed to send Bilibili live status", "error", err)
+		}
+
+	case AzutvTaskTypeBilibiliDynamic:
+		if err := SendBilibiliDynamic(ctx, config.GetBilibiliDynamicUIDs()); err != nil {
+			slog.Error("Failed to send Bilibili dynamics", "error", err)
+		}
+
+	case AzutvTaskTypeBilibiliRanking:
+		if err := SendBilibiliRanking(ctx, config.GetBilibiliRankingBoards()); err != nil {
+			slog.Error("Failed to send Bilibili ranking", "error", err)
+		}
+
 	default:
 		slog.Error(fmt.Sprintf("invalid task type %q", *task))
 		return
@@ -70,21 +148,95 @@ func RunService(task *string) {
 }
 
 // RunServiceWithParams 运行需要参数的服务
-func RunServiceWithParams(task string, params map[string]string) error {
+func RunServiceWithParams(ctx context.Context, task string, params map[string]string) error {
 	switch AzutvTaskType(task) {
+	case AzutvTaskTypeGithubTrending:
+		SendGithubTrending(params["language"], params["since"])
+		return nil
+
 	case AzutvTaskTypeYouTubeUser:
 		userID, ok := params["userID"]
 		if !ok || userID == "" {
 			return errors.New("YouTube user service requires 'userID' parameter")
 		}
-		return SendYouTubeUserInfo(userID)
+		// 参数中的 sections 优先于配置
+		sections := utils.SplitList(params["sections"])
+		if len(sections) == 0 {
+			sections = config.GetYouTubeUserSections()
+		}
+		kinds, err := ParseYouTubeVideoKinds(sections)
+		if err != nil {
+			return err
+		}
+		return SendYouTubeUserInfo(ctx, userID, kinds)
 
 	case AzutvTaskTypeBilibiliUser:
 		uid, ok := params["uid"]
 		if !ok || uid == "" {
 			return errors.New("Bilibili user service requires 'uid' parameter")
 		}
-		return SendBilibiliUserInfo(uid)
+		// 参数中的 order 优先于配置
+		orderParam := params["order"]
+		if orderParam == "" {
+			orderParam = config.GetBilibiliVideoOrder()
+		}
+		order, err := ParseBilibiliVideoOrder(orderParam)
+		if err != nil {
+			return err
+		}
+		// 参数中的 sections 优先于配置
+		sectionNames := utils.SplitList(params["sections"])
+		if len(sectionNames) == 0 {
+			sectionNames = config.GetBilibiliUserSections()
+		}
+		sections, err := ParseBilibiliUserSections(sectionNames)
+		if err != nil {
+			return err
+		}
+		return SendBilibiliUserInfo(ctx, uid, order, sections)
+
+	case AzutvTaskTypeYouTubeWatch:
+		channels := utils.SplitList(params["channels"])
+		if len(channels) == 0 {
+			return errors.New("YouTube watch service requires 'channels' parameter")
+		}
+		return SendYouTubeWatch(ctx, channels)
+
+	case AzutvTaskTypeYouTubeLive:
+		channels := utils.SplitList(params["channels"])
+		if len(channels) == 0 {
+			return errors.New("YouTube live service requires 'channels' parameter")
+		}
+		return SendYouTubeLive(ctx, channels)
+
+	case AzutvTaskTypeYouTubePlaylist:
+		playlists := utils.SplitList(params["playlists"])
+		if len(playlists) == 0 {
+			return errors.New("YouTube playlist service requires 'playlists' parameter")
+		}
+		return SendYouTubePlaylist(ctx, playlists)
+
+	case AzutvTaskTypeBilibiliLive:
+		uids := utils.SplitList(params["uids"])
+		if len(uids) == 0 {
+			return errors.New("Bilibili live service requires 'uids' parameter")
+		}
+		return SendBilibiliLive(ctx, uids)
+
+	case AzutvTaskTypeBilibiliDynamic:
+		uids := utils.SplitList(params["uids"])
+		if len(uids) == 0 {
+			return errors.New("Bilibili dynamic service requires 'uids' parameter")
+		}
+		return SendBilibiliDynamic(ctx, uids)
+
+	case AzutvTaskTypeBilibiliRanking:
+		// 参数中的 boards 优先于配置
+		boards := utils.SplitList(params["boards"])
+		if len(boards) == 0 {
+			boards = config.GetBilibiliRankingBoards()
+		}
+		return SendBilibiliRanking(ctx, boards)
 
 	default:
 		return errors.Errorf("unsupported task type for parameterized service: %s", task)
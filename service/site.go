@@ -0,0 +1,22 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/site"
+	"log/slog"
+)
+
+// GenerateArchiveSite 将全部历史记录渲染为静态站点
+func GenerateArchiveSite(outDir string, format string) error {
+	store := history.NewStore(config.GetDataDir())
+	if err := site.Generate(store, site.Options{
+		OutDir:   outDir,
+		Format:   site.Format(format),
+		Location: config.GetLocation(),
+	}); err != nil {
+		return err
+	}
+	slog.Info("archive site generated", "dir", outDir, "format", format)
+	return nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/utils"
+	"embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// 默认消息模板，可被 templates_dir 下的同名文件覆盖
+//
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// messageSeparator 模板中 {{split}} 输出的分隔符，渲染后按它拆分为多条消息
+const messageSeparator = "\x00split\x00"
+
+var templateFuncs = template.FuncMap{
+	"inc": func(i int) int {
+		return i + 1
+	},
+	// every 判断第 i 个（从0开始）条目之后是否正好凑满 n 个
+	"every": func(n int, i int) bool {
+		return n > 0 && (i+1)%n == 0
+	},
+	"split": func() string {
+		return messageSeparator
+	},
+	"formatCount":    templateFormatCount,
+	"formatDuration": formatDuration,
+	"trendEmoji": func(trend any) string {
+		return oriconRankingTrendToEmoji(OriconRankingTrend(fmt.Sprint(trend)))
+	},
+	"formatTime": formatTime,
+	"ofKind": func(kind string, videos []YouTubeVideoInfo) []YouTubeVideoInfo {
+		return filterYouTubeVideos(YouTubeVideoKind(kind), videos)
+	},
+	"truncate": truncateRunes,
+	"wrap": func(width int, s string) string {
+		return utils.WrapAtWidth(s, width)
+	},
+}
+
+func templateFormatCount(v any) string {
+	switch n := v.(type) {
+	case int:
+		return formatCount(int64(n))
+	case int64:
+		return formatCount(n)
+	case float64:
+		return formatCount(int64(n))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatTime 按配置的时区格式化时间，零值返回空字符串
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(config.GetLocation()).Format("2006-01-02 15:04 MST")
+}
+
+// truncateRunes 将字符串截断到 n 个字符，超出部分以省略号表示
+func truncateRunes(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// loadMessageTemplate 按 <task>.<sink>.tmpl、<task>.tmpl、内置模板的顺序查找模板
+func loadMessageTemplate(task AzutvTaskType, sink string) (*template.Template, error) {
+	name := string(task) + ".tmpl"
+	if dir := config.GetTemplatesDir(); dir != "" {
+		var candidates []string
+		if sink != "" {
+			candidates = append(candidates, filepath.Join(dir, string(task)+"."+sink+".tmpl"))
+		}
+		candidates = append(candidates, filepath.Join(dir, name))
+		for _, path := range candidates {
+			text, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read template %s", path)
+			}
+			t, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse template %s", path)
+			}
+			return t, nil
+		}
+	}
+
+	t, err := template.New(name).Funcs(templateFuncs).ParseFS(defaultTemplates, "templates/"+name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "no template for task %s", task)
+	}
+	return t, nil
+}
+
+// renderMessages 用任务和目标对应的模板渲染数据，并拆分为不超过 Discord 长度限制的消息
+func renderMessages(task AzutvTaskType, sink string, data any) ([]string, error) {
+	t, err := loadMessageTemplate(task, sink)
+	if err != nil {
+		return nil, err
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to render %s", task)
+	}
+
+	var messages []string
+	for _, part := range strings.Split(buf.String(), messageSeparator) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		messages = append(messages, splitByLength(part, DiscordMessageLimit)...)
+	}
+	return messages, nil
+}
+
+// renderDefaultMessages 用默认目标的模板渲染，失败时记录日志并返回空
+func renderDefaultMessages(task AzutvTaskType, data any) []string {
+	messages, err := renderMessages(task, "", data)
+	if err != nil {
+		slog.Warn(err.Error())
+	}
+	return messages
+}
+
+// splitByLength 按行将消息拆分为不超过 limit 个字符的多段，单行过长时强制截断
+func splitByLength(s string, limit int) []string {
+	if utf8.RuneCountInString(s) <= limit {
+		return []string{s}
+	}
+
+	var parts []string
+	var current strings.Builder
+	length := 0
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+			length = 0
+		}
+	}
+	for _, line := range strings.SplitAfter(s, "\n") {
+		for utf8.RuneCountInString(line) > limit {
+			flush()
+			runes := []rune(line)
+			parts = append(parts, string(runes[:limit]))
+			line = string(runes[limit:])
+		}
+		lineLength := utf8.RuneCountInString(line)
+		if length+lineLength > limit {
+			flush()
+		}
+		current.WriteString(line)
+		length += lineLength
+	}
+	flush()
+	return parts
+}
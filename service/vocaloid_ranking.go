@@ -1,12 +1,11 @@
 package service
 
 import (
-	"azuserver/config"
+	"azuserver/lib/history"
 	"context"
 	"fmt"
 	"log/slog"
 	"strconv"
-	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/pkg/errors"
@@ -15,16 +14,21 @@ import (
 )
 
 func SendVocaloidRanking() {
-	messages, err := GetVocaloidRankingMessage()
+	entries, err := FetchVocaloidRanking()
 	if err != nil {
 		slog.Warn(err.Error())
 		return
 	}
-	if err := SendMessageToDiscord(
-		messages,
-		config.GetDiscordChatWebhookUrl(),
-		ServiceNameVocaloidnRanking,
-	); err != nil {
+	items := make([]history.Item, 0, len(entries))
+	for idx, entry := range entries {
+		items = append(items, newHistoryItem(strconv.Itoa(entry.ID), idx+1, entry.Name+" - "+entry.Artist, entry.Url, "", entry))
+	}
+	if err := publish(publication{
+		Task:     AzutvTaskTypeVocaloidRanking,
+		Username: ServiceNameVocaloidnRanking,
+		Items:    items,
+		Data:     entries,
+	}); err != nil {
 		slog.Warn(errors.Wrapf(err, "failed to send Vocaloid Ranking to Discord").Error())
 		return
 	}
@@ -63,7 +67,7 @@ func fetchPvYoutubeLinkById(id string) (string, error) {
 		return "", errors.Wrapf(err, "failed to get 'pvService' for song: %s", id)
 	}
 	if resp.StatusCode() != 200 {
-		return "", fmt.Errorf("error status code %q", resp.StatusCode())
+		return "", fmt.Errorf("error status code %d", resp.StatusCode())
 	}
 
 	var nicoUrl, youtubeUrl, bandcampUrl string
@@ -114,6 +118,14 @@ func fetchPvYouTubeLinks(ctx context.Context, entries []VocaloidRankingEntry) er
 }
 
 func GetVocaloidRankingMessage() ([]string, error) {
+	entries, err := FetchVocaloidRanking()
+	if err != nil {
+		return nil, err
+	}
+	return FormatVocaloidRankingMessages(entries), nil
+}
+
+func FetchVocaloidRanking() ([]VocaloidRankingEntry, error) {
 	var entries []VocaloidRankingEntry
 	client := resty.New()
 	resp, err := client.R().
@@ -128,31 +140,17 @@ func GetVocaloidRankingMessage() ([]string, error) {
 		return nil, errors.Wrapf(err, "failed to send fetch Vocaloid ranking data")
 	}
 	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("error status code %q", resp.StatusCode())
+		return nil, fmt.Errorf("error status code %d", resp.StatusCode())
 	}
 
 	err = fetchPvYouTubeLinks(context.Background(), entries)
 	if err != nil {
 		return nil, err
 	}
+	return entries, nil
+}
 
-	var vocaloidRank strings.Builder
-	var messages []string
-	for idx, entry := range entries {
-		if entry.Url == "" {
-			vocaloidRank.WriteString(fmt.Sprintf("%d. %s - %s\n", idx+1, entry.Name, entry.Artist))
-		} else {
-			vocaloidRank.WriteString(fmt.Sprintf("%d. [%s](<%s>) - %s\n", idx+1, entry.Name, entry.Url, entry.Artist))
-		}
-		if (idx+1)%10 == 0 {
-			messages = append(messages, vocaloidRank.String())
-			vocaloidRank.Reset()
-		}
-	}
-	left := vocaloidRank.String()
-	if left != "" {
-		messages = append(messages, left)
-	}
-
-	return messages, nil
+// FormatVocaloidRankingMessages 用默认模板渲染 Vocaloid 排行消息
+func FormatVocaloidRankingMessages(entries []VocaloidRankingEntry) []string {
+	return renderDefaultMessages(AzutvTaskTypeVocaloidRanking, entries)
 }
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/state"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const youtubeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// youtubeChannelCacheName 频道ID解析结果的缓存文件名
+const youtubeChannelCacheName = "youtube_channels"
+
+// youtubeChannelCacheTTL 缓存的解析结果超过该时间后重新解析，解析失败时仍使用旧结果
+const youtubeChannelCacheTTL = 30 * 24 * time.Hour
+
+var (
+	youtubeChannelIDRegex = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+	// 按可靠程度排列的页面中频道ID的位置
+	youtubeChannelIDPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`<link rel="canonical" href="https://www\.youtube\.com/channel/(UC[0-9A-Za-z_-]{22})"`),
+		regexp.MustCompile(`"externalId":"(UC[0-9A-Za-z_-]{22})"`),
+		regexp.MustCompile(`<meta property="og:url" content="https://www\.youtube\.com/channel/(UC[0-9A-Za-z_-]{22})"`),
+		regexp.MustCompile(`<meta itemprop="identifier" content="(UC[0-9A-Za-z_-]{22})"`),
+	}
+)
+
+type youtubeChannelCacheEntry struct {
+	ChannelID  string    `json:"channelId"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+var youtubeChannelCache struct {
+	sync.Mutex
+	loaded  bool
+	entries map[string]youtubeChannelCacheEntry
+}
+
+// isYouTubeChannelID 判断是否为 UC 开头的频道ID
+func isYouTubeChannelID(userID string) bool {
+	return youtubeChannelIDRegex.MatchString(userID)
+}
+
+// youtubeChannelURL 返回频道ID对应的频道地址
+func youtubeChannelURL(channelID string) string {
+	return fmt.Sprintf("https://www.youtube.com/channel/%s", channelID)
+}
+
+// youtubeCandidateURLs 根据用户输入列出可能的频道地址，支持完整链接、@handle、/c/ 自定义名称和 /user/ 旧用户名
+func youtubeCandidateURLs(userID string) []string {
+	userID = strings.TrimSpace(userID)
+	if u, err := url.Parse(userID); err == nil && strings.HasSuffix(u.Hostname(), "youtube.com") {
+		return []string{userID}
+	}
+	if strings.HasPrefix(userID, "@") {
+		return []string{"https://www.youtube.com/" + url.PathEscape(userID)}
+	}
+	name := url.PathEscape(userID)
+	return []string{
+		"https://www.youtube.com/c/" + name,
+		"https://www.youtube.com/user/" + name,
+		"https://www.youtube.com/@" + name,
+	}
+}
+
+// channelIDFromURL 从 /channel/UC... 形式的链接中直接取出频道ID
+func channelIDFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "channel" && isYouTubeChannelID(parts[1]) {
+		return parts[1]
+	}
+	return ""
+}
+
+// ResolveYouTubeChannelID 将 @handle、自定义名称、旧用户名或频道链接解析为 UC 开头的频道ID，结果缓存在本地
+func ResolveYouTubeChannelID(ctx context.Context, userID string) (string, error) {
+	userID = strings.TrimSpace(userID)
+	if isYouTubeChannelID(userID) {
+		return userID, nil
+	}
+	if id := channelIDFromURL(userID); id != "" {
+		return id, nil
+	}
+
+	youtubeChannelCache.Lock()
+	loadYouTubeChannelCache()
+	cached, ok := youtubeChannelCache.entries[userID]
+	youtubeChannelCache.Unlock()
+	if ok && time.Since(cached.ResolvedAt) < youtubeChannelCacheTTL {
+		return cached.ChannelID, nil
+	}
+
+	// 请求期间不持有锁，多个频道可以同时解析
+	channelID, err := fetchYouTubeChannelID(ctx, userID)
+	if err != nil {
+		if ok {
+			slog.Warn("Failed to refresh YouTube channel ID, using cached value", "userID", userID, "error", err)
+			return cached.ChannelID, nil
+		}
+		return "", err
+	}
+
+	youtubeChannelCache.Lock()
+	defer youtubeChannelCache.Unlock()
+	youtubeChannelCache.entries[userID] = youtubeChannelCacheEntry{
+		ChannelID:  channelID,
+		ResolvedAt: time.Now(),
+	}
+	if !IsDryRun() {
+		store := state.NewStore(config.GetDataDir())
+		if err := store.Save(youtubeChannelCacheName, youtubeChannelCache.entries); err != nil {
+			slog.Warn("Failed to save YouTube channel cache", "error", err)
+		}
+	}
+	return channelID, nil
+}
+
+func loadYouTubeChannelCache() {
+	if youtubeChannelCache.loaded {
+		return
+	}
+	youtubeChannelCache.loaded = true
+	youtubeChannelCache.entries = make(map[string]youtubeChannelCacheEntry)
+	store := state.NewStore(config.GetDataDir())
+	if _, err := store.Load(youtubeChannelCacheName, &youtubeChannelCache.entries); err != nil {
+		slog.Warn("Failed to load YouTube channel cache", "error", err)
+	}
+}
+
+// fetchYouTubeChannelID 依次访问候选地址（跟随重定向），从页面中读取频道ID
+func fetchYouTubeChannelID(ctx context.Context, userID string) (string, error) {
+	// 解析结果在任务间共享，使用全局的界面语言
+	locale := YouTubeLocaleForTask("")
+	var lastErr error
+	for _, candidate := range youtubeCandidateURLs(userID) {
+		if err := waitForURL(ctx, candidate); err != nil {
+			return "", err
+		}
+		resp, err := locale.newRequest(ctx).Get(locale.URL(candidate))
+		if err != nil {
+			lastErr = errors.Wrapf(err, "failed to visit YouTube channel: %s", candidate)
+			continue
+		}
+		if resp.StatusCode() != 200 {
+			lastErr = fmt.Errorf("error status code %d for %s", resp.StatusCode(), candidate)
+			continue
+		}
+
+		// 重定向后的最终地址本身可能就是 /channel/UC...
+		if id := channelIDFromURL(resp.RawResponse.Request.URL.String()); id != "" {
+			return id, nil
+		}
+		body := resp.String()
+		for _, re := range youtubeChannelIDPatterns {
+			if matches := re.FindStringSubmatch(body); len(matches) > 1 {
+				return matches[1], nil
+			}
+		}
+		lastErr = errors.Errorf("channel ID not found on %s", candidate)
+	}
+	return "", errors.Wrapf(lastErr, "failed to resolve YouTube channel ID for %s", userID)
+}
@@ -0,0 +1,530 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ytInitialData 在页面脚本中的几种赋值写法
+var ytInitialDataMarkers = []string{
+	"var ytInitialData = ",
+	"window[\"ytInitialData\"] = ",
+	"ytInitialData = ",
+}
+
+// ytInitialPlayerResponse 在视频页面脚本中的几种赋值写法
+var ytInitialPlayerResponseMarkers = []string{
+	"var ytInitialPlayerResponse = ",
+	"window[\"ytInitialPlayerResponse\"] = ",
+	"ytInitialPlayerResponse = ",
+}
+
+// extractYtInitialData 从页面脚本中截取 ytInitialData 对象并解码到 v，脚本中没有该对象时返回 false
+func extractYtInitialData(script string, v any) (bool, error) {
+	found, err := extractScriptJSON(script, ytInitialDataMarkers, v)
+	return found, errors.Wrapf(err, "failed to decode ytInitialData")
+}
+
+// extractYtInitialPlayerResponse 从视频页面脚本中截取 ytInitialPlayerResponse 对象并解码到 v
+func extractYtInitialPlayerResponse(script string, v any) (bool, error) {
+	found, err := extractScriptJSON(script, ytInitialPlayerResponseMarkers, v)
+	return found, errors.Wrapf(err, "failed to decode ytInitialPlayerResponse")
+}
+
+// ytText YouTube 的文本字段，可能是 simpleText 或多段 runs
+type ytText struct {
+	SimpleText string `json:"simpleText"`
+	Runs       []struct {
+		Text string `json:"text"`
+	} `json:"runs"`
+}
+
+func (t ytText) String() string {
+	if t.SimpleText != "" {
+		return t.SimpleText
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type ytThumbnails struct {
+	Thumbnails []struct {
+		URL    string `json:"url"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"thumbnails"`
+}
+
+// Largest 返回分辨率最高的缩略图地址
+func (t ytThumbnails) Largest() string {
+	url, best := "", 0
+	for _, thumb := range t.Thumbnails {
+		if thumb.Width*thumb.Height >= best {
+			url, best = thumb.URL, thumb.Width*thumb.Height
+		}
+	}
+	return url
+}
+
+// ytVideoRenderer 频道视频列表中的单个视频
+type ytVideoRenderer struct {
+	VideoID            string       `json:"videoId"`
+	Title              ytText       `json:"title"`
+	Thumbnail          ytThumbnails `json:"thumbnail"`
+	ViewCountText      ytText       `json:"viewCountText"`
+	ShortViewCountText ytText       `json:"shortViewCountText"`
+	PublishedTimeText  ytText       `json:"publishedTimeText"`
+	LengthText         ytText       `json:"lengthText"`
+	DescriptionSnippet ytText       `json:"descriptionSnippet"`
+	// 预定的直播和首映才有 upcomingEventData，startTime 为 Unix 秒
+	UpcomingEventData *struct {
+		StartTime string `json:"startTime"`
+		// 如 "Premieres DATE_PLACEHOLDER"、"Scheduled for DATE_PLACEHOLDER"
+		UpcomingEventText ytText `json:"upcomingEventText"`
+	} `json:"upcomingEventData"`
+	Badges []struct {
+		MetadataBadgeRenderer struct {
+			Style string `json:"style"`
+			Label string `json:"label"`
+		} `json:"metadataBadgeRenderer"`
+	} `json:"badges"`
+	ThumbnailOverlays []struct {
+		ThumbnailOverlayTimeStatusRenderer *struct {
+			Style string `json:"style"`
+			Text  ytText `json:"text"`
+		} `json:"thumbnailOverlayTimeStatusRenderer"`
+	} `json:"thumbnailOverlays"`
+}
+
+// ytPremiereWords 各界面语言中标记首映的文本，出现在预定提示、徽章或缩略图标签中
+var ytPremiereWords = []string{"premier", "プレミア", "首映", "최초 공개"}
+
+// IsLive 判断视频是否正在直播
+func (r *ytVideoRenderer) IsLive() bool {
+	for _, badge := range r.Badges {
+		if badge.MetadataBadgeRenderer.Style == "BADGE_STYLE_TYPE_LIVE_NOW" {
+			return true
+		}
+	}
+	for _, overlay := range r.ThumbnailOverlays {
+		if overlay.ThumbnailOverlayTimeStatusRenderer != nil && overlay.ThumbnailOverlayTimeStatusRenderer.Style == "LIVE" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUpcoming 判断视频是否为预定的直播或首映
+func (r *ytVideoRenderer) IsUpcoming() bool {
+	return r.UpcomingEventData != nil
+}
+
+// IsPremiere 判断视频是否为首映。列表中没有 isLiveContent，只能按预定提示、
+// 徽章（"PREMIERING NOW"）或缩略图标签（"PREMIERE"）中的文本判断
+func (r *ytVideoRenderer) IsPremiere() bool {
+	texts := make([]string, 0, 1+len(r.Badges)+len(r.ThumbnailOverlays))
+	if r.UpcomingEventData != nil {
+		texts = append(texts, r.UpcomingEventData.UpcomingEventText.String())
+	}
+	for _, badge := range r.Badges {
+		texts = append(texts, badge.MetadataBadgeRenderer.Label)
+	}
+	for _, overlay := range r.ThumbnailOverlays {
+		if overlay.ThumbnailOverlayTimeStatusRenderer != nil {
+			texts = append(texts, overlay.ThumbnailOverlayTimeStatusRenderer.Text.String())
+		}
+	}
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, word := range ytPremiereWords {
+			if strings.Contains(text, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ScheduledStart 返回预定的开始时间，未预定时返回零值
+func (r *ytVideoRenderer) ScheduledStart() time.Time {
+	if r.UpcomingEventData == nil {
+		return time.Time{}
+	}
+	return parseUnixSeconds(r.UpcomingEventData.StartTime)
+}
+
+// parseUnixSeconds 解析字符串形式的 Unix 秒，无法解析时返回零值
+func parseUnixSeconds(s string) time.Time {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// ytReelItemRenderer Shorts 标签页中的单个短视频（旧版结构）
+type ytReelItemRenderer struct {
+	VideoID       string       `json:"videoId"`
+	Headline      ytText       `json:"headline"`
+	ViewCountText ytText       `json:"viewCountText"`
+	Thumbnail     ytThumbnails `json:"thumbnail"`
+}
+
+// ytShortsLockupViewModel Shorts 标签页中的单个短视频（新版结构）
+type ytShortsLockupViewModel struct {
+	OverlayMetadata struct {
+		PrimaryText struct {
+			Content string `json:"content"`
+		} `json:"primaryText"`
+		SecondaryText struct {
+			Content string `json:"content"`
+		} `json:"secondaryText"`
+	} `json:"overlayMetadata"`
+	OnTap struct {
+		InnertubeCommand struct {
+			ReelWatchEndpoint struct {
+				VideoID string `json:"videoId"`
+			} `json:"reelWatchEndpoint"`
+		} `json:"innertubeCommand"`
+	} `json:"onTap"`
+}
+
+// ytShort 两种 Shorts 结构的共同字段
+type ytShort struct {
+	VideoID   string
+	Title     string
+	ViewCount string
+}
+
+type ytRichItemRenderer struct {
+	Content struct {
+		VideoRenderer         *ytVideoRenderer         `json:"videoRenderer"`
+		ReelItemRenderer      *ytReelItemRenderer      `json:"reelItemRenderer"`
+		ShortsLockupViewModel *ytShortsLockupViewModel `json:"shortsLockupViewModel"`
+	} `json:"content"`
+}
+
+// ytTabRenderer 频道页面的一个标签页（视频、Shorts、直播等）
+type ytTabRenderer struct {
+	Title    string `json:"title"`
+	Selected bool   `json:"selected"`
+	Endpoint struct {
+		CommandMetadata struct {
+			WebCommandMetadata struct {
+				URL string `json:"url"`
+			} `json:"webCommandMetadata"`
+		} `json:"commandMetadata"`
+	} `json:"endpoint"`
+	Content struct {
+		RichGridRenderer *struct {
+			Contents []struct {
+				RichItemRenderer *ytRichItemRenderer `json:"richItemRenderer"`
+			} `json:"contents"`
+		} `json:"richGridRenderer"`
+	} `json:"content"`
+}
+
+// ytBrowseData 频道页面的 ytInitialData
+type ytBrowseData struct {
+	Metadata struct {
+		ChannelMetadataRenderer struct {
+			Title      string `json:"title"`
+			ExternalID string `json:"externalId"`
+		} `json:"channelMetadataRenderer"`
+	} `json:"metadata"`
+	Contents struct {
+		TwoColumnBrowseResultsRenderer struct {
+			Tabs []struct {
+				TabRenderer *ytTabRenderer `json:"tabRenderer"`
+			} `json:"tabs"`
+		} `json:"twoColumnBrowseResultsRenderer"`
+	} `json:"contents"`
+}
+
+// SelectedTab 返回当前选中的标签页
+func (d *ytBrowseData) SelectedTab() *ytTabRenderer {
+	for _, tab := range d.Contents.TwoColumnBrowseResultsRenderer.Tabs {
+		if tab.TabRenderer != nil && tab.TabRenderer.Selected {
+			return tab.TabRenderer
+		}
+	}
+	return nil
+}
+
+// richItems 返回标签页网格中的全部条目
+func (t *ytTabRenderer) richItems() []*ytRichItemRenderer {
+	if t == nil || t.Content.RichGridRenderer == nil {
+		return nil
+	}
+	var items []*ytRichItemRenderer
+	for _, item := range t.Content.RichGridRenderer.Contents {
+		if item.RichItemRenderer != nil {
+			items = append(items, item.RichItemRenderer)
+		}
+	}
+	return items
+}
+
+// VideoRenderers 返回标签页网格中的全部视频
+func (t *ytTabRenderer) VideoRenderers() []*ytVideoRenderer {
+	var videos []*ytVideoRenderer
+	for _, item := range t.richItems() {
+		if item.Content.VideoRenderer != nil {
+			videos = append(videos, item.Content.VideoRenderer)
+		}
+	}
+	return videos
+}
+
+// Shorts 返回 Shorts 标签页网格中的全部短视频
+func (t *ytTabRenderer) Shorts() []ytShort {
+	var shorts []ytShort
+	for _, item := range t.richItems() {
+		if reel := item.Content.ReelItemRenderer; reel != nil {
+			shorts = append(shorts, ytShort{
+				VideoID:   reel.VideoID,
+				Title:     reel.Headline.String(),
+				ViewCount: reel.ViewCountText.String(),
+			})
+		} else if lockup := item.Content.ShortsLockupViewModel; lockup != nil {
+			shorts = append(shorts, ytShort{
+				VideoID:   lockup.OnTap.InnertubeCommand.ReelWatchEndpoint.VideoID,
+				Title:     lockup.OverlayMetadata.PrimaryText.Content,
+				ViewCount: lockup.OverlayMetadata.SecondaryText.Content,
+			})
+		}
+	}
+	return shorts
+}
+
+// ytPlayerResponse 视频页面的 ytInitialPlayerResponse，频道的 /live 页面会直接返回当前或预定的直播
+type ytPlayerResponse struct {
+	VideoDetails struct {
+		VideoID       string `json:"videoId"`
+		Title         string `json:"title"`
+		Author        string `json:"author"`
+		ChannelID     string `json:"channelId"`
+		IsLive        bool   `json:"isLive"`
+		IsUpcoming    bool   `json:"isUpcoming"`
+		IsLiveContent bool   `json:"isLiveContent"`
+	} `json:"videoDetails"`
+	PlayabilityStatus struct {
+		LiveStreamability *struct {
+			LiveStreamabilityRenderer struct {
+				OfflineSlate *struct {
+					LiveStreamOfflineSlateRenderer struct {
+						ScheduledStartTime string `json:"scheduledStartTime"`
+					} `json:"liveStreamOfflineSlateRenderer"`
+				} `json:"offlineSlate"`
+			} `json:"liveStreamabilityRenderer"`
+		} `json:"liveStreamability"`
+	} `json:"playabilityStatus"`
+	Microformat struct {
+		PlayerMicroformatRenderer struct {
+			LiveBroadcastDetails *struct {
+				IsLiveNow      bool   `json:"isLiveNow"`
+				StartTimestamp string `json:"startTimestamp"`
+			} `json:"liveBroadcastDetails"`
+		} `json:"playerMicroformatRenderer"`
+	} `json:"microformat"`
+}
+
+// IsLive 判断是否正在直播
+func (p *ytPlayerResponse) IsLive() bool {
+	if p.VideoDetails.IsLive {
+		return true
+	}
+	details := p.Microformat.PlayerMicroformatRenderer.LiveBroadcastDetails
+	return details != nil && details.IsLiveNow
+}
+
+// ScheduledStart 返回预定的开始时间，未预定时返回零值
+func (p *ytPlayerResponse) ScheduledStart() time.Time {
+	if ls := p.PlayabilityStatus.LiveStreamability; ls != nil && ls.LiveStreamabilityRenderer.OfflineSlate != nil {
+		return parseUnixSeconds(ls.LiveStreamabilityRenderer.OfflineSlate.LiveStreamOfflineSlateRenderer.ScheduledStartTime)
+	}
+	return time.Time{}
+}
+
+// ytPlaylistVideoRenderer 播放列表中的单个视频
+type ytPlaylistVideoRenderer struct {
+	VideoID         string `json:"videoId"`
+	Title           ytText `json:"title"`
+	Index           ytText `json:"index"`
+	LengthText      ytText `json:"lengthText"`
+	ShortBylineText struct {
+		Runs []struct {
+			Text               string `json:"text"`
+			NavigationEndpoint struct {
+				BrowseEndpoint struct {
+					BrowseID string `json:"browseId"`
+				} `json:"browseEndpoint"`
+			} `json:"navigationEndpoint"`
+		} `json:"runs"`
+	} `json:"shortBylineText"`
+}
+
+// ytContinuationItemRenderer 列表末尾的“加载更多”，token 用于通过 browse 接口获取下一页
+type ytContinuationItemRenderer struct {
+	ContinuationEndpoint struct {
+		ContinuationCommand struct {
+			Token string `json:"token"`
+		} `json:"continuationCommand"`
+	} `json:"continuationEndpoint"`
+}
+
+// ytPlaylistItem 播放列表中的一项，最后一项可能是下一页的 continuation
+type ytPlaylistItem struct {
+	PlaylistVideoRenderer    *ytPlaylistVideoRenderer    `json:"playlistVideoRenderer"`
+	ContinuationItemRenderer *ytContinuationItemRenderer `json:"continuationItemRenderer"`
+}
+
+// ytPlaylistData 播放列表页面的 ytInitialData
+type ytPlaylistData struct {
+	Contents struct {
+		TwoColumnBrowseResultsRenderer struct {
+			Tabs []struct {
+				TabRenderer *struct {
+					Content struct {
+						SectionListRenderer struct {
+							Contents []struct {
+								ItemSectionRenderer struct {
+									Contents []struct {
+										PlaylistVideoListRenderer *struct {
+											Contents []ytPlaylistItem `json:"contents"`
+										} `json:"playlistVideoListRenderer"`
+									} `json:"contents"`
+								} `json:"itemSectionRenderer"`
+							} `json:"contents"`
+						} `json:"sectionListRenderer"`
+					} `json:"content"`
+				} `json:"tabRenderer"`
+			} `json:"tabs"`
+		} `json:"twoColumnBrowseResultsRenderer"`
+	} `json:"contents"`
+	Metadata struct {
+		PlaylistMetadataRenderer struct {
+			Title string `json:"title"`
+		} `json:"playlistMetadataRenderer"`
+	} `json:"metadata"`
+	Sidebar struct {
+		PlaylistSidebarRenderer struct {
+			Items []struct {
+				PrimaryInfo *struct {
+					Stats []ytText `json:"stats"`
+				} `json:"playlistSidebarPrimaryInfoRenderer"`
+				SecondaryInfo *struct {
+					VideoOwner struct {
+						VideoOwnerRenderer struct {
+							Title ytText `json:"title"`
+						} `json:"videoOwnerRenderer"`
+					} `json:"videoOwner"`
+				} `json:"playlistSidebarSecondaryInfoRenderer"`
+			} `json:"items"`
+		} `json:"playlistSidebarRenderer"`
+	} `json:"sidebar"`
+}
+
+// items 返回页面中已加载的播放列表条目（首次加载最多 100 个）
+func (d *ytPlaylistData) items() []ytPlaylistItem {
+	var items []ytPlaylistItem
+	for _, tab := range d.Contents.TwoColumnBrowseResultsRenderer.Tabs {
+		if tab.TabRenderer == nil {
+			continue
+		}
+		for _, section := range tab.TabRenderer.Content.SectionListRenderer.Contents {
+			for _, item := range section.ItemSectionRenderer.Contents {
+				if item.PlaylistVideoListRenderer != nil {
+					items = append(items, item.PlaylistVideoListRenderer.Contents...)
+				}
+			}
+		}
+	}
+	return items
+}
+
+// VideoRenderers 返回页面中已加载的播放列表视频
+func (d *ytPlaylistData) VideoRenderers() []*ytPlaylistVideoRenderer {
+	return playlistVideoRenderers(d.items())
+}
+
+// Continuation 返回下一页的 token，列表已全部加载时为空
+func (d *ytPlaylistData) Continuation() string {
+	return playlistContinuation(d.items())
+}
+
+// Stats 返回侧栏中的统计文本（视频数、观看次数、更新时间）
+func (d *ytPlaylistData) Stats() []string {
+	var stats []string
+	for _, item := range d.Sidebar.PlaylistSidebarRenderer.Items {
+		if item.PrimaryInfo != nil {
+			for _, stat := range item.PrimaryInfo.Stats {
+				stats = append(stats, stat.String())
+			}
+		}
+	}
+	return stats
+}
+
+// Owner 返回播放列表创建者的名称
+func (d *ytPlaylistData) Owner() string {
+	for _, item := range d.Sidebar.PlaylistSidebarRenderer.Items {
+		if item.SecondaryInfo != nil {
+			return item.SecondaryInfo.VideoOwner.VideoOwnerRenderer.Title.String()
+		}
+	}
+	return ""
+}
+
+// ytPlaylistContinuation browse 接口返回的播放列表下一页
+type ytPlaylistContinuation struct {
+	OnResponseReceivedActions []struct {
+		AppendContinuationItemsAction struct {
+			ContinuationItems []ytPlaylistItem `json:"continuationItems"`
+		} `json:"appendContinuationItemsAction"`
+	} `json:"onResponseReceivedActions"`
+}
+
+// items 返回本页的全部条目
+func (c *ytPlaylistContinuation) items() []ytPlaylistItem {
+	var items []ytPlaylistItem
+	for _, action := range c.OnResponseReceivedActions {
+		items = append(items, action.AppendContinuationItemsAction.ContinuationItems...)
+	}
+	return items
+}
+
+// VideoRenderers 返回本页的播放列表视频
+func (c *ytPlaylistContinuation) VideoRenderers() []*ytPlaylistVideoRenderer {
+	return playlistVideoRenderers(c.items())
+}
+
+// Continuation 返回再下一页的 token，已是最后一页时为空
+func (c *ytPlaylistContinuation) Continuation() string {
+	return playlistContinuation(c.items())
+}
+
+func playlistVideoRenderers(items []ytPlaylistItem) []*ytPlaylistVideoRenderer {
+	var videos []*ytPlaylistVideoRenderer
+	for _, item := range items {
+		if item.PlaylistVideoRenderer != nil {
+			videos = append(videos, item.PlaylistVideoRenderer)
+		}
+	}
+	return videos
+}
+
+func playlistContinuation(items []ytPlaylistItem) string {
+	for _, item := range items {
+		if item.ContinuationItemRenderer != nil {
+			return item.ContinuationItemRenderer.ContinuationEndpoint.ContinuationCommand.Token
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestYtVideoRendererStreamKind(t *testing.T) {
+	tests := []struct {
+		name         string
+		renderer     string
+		wantLive     bool
+		wantUpcoming bool
+		wantPremiere bool
+	}{
+		{
+			name:         "scheduled stream",
+			renderer:     `{"videoId":"a","upcomingEventData":{"startTime":"1700000000","upcomingEventText":{"runs":[{"text":"Scheduled for "},{"text":"DATE_PLACEHOLDER"}]}},"thumbnailOverlays":[{"thumbnailOverlayTimeStatusRenderer":{"style":"UPCOMING","text":{"simpleText":"UPCOMING"}}}]}`,
+			wantUpcoming: true,
+		},
+		{
+			name:         "scheduled premiere",
+			renderer:     `{"videoId":"b","upcomingEventData":{"startTime":"1700000000","upcomingEventText":{"runs":[{"text":"Premieres "},{"text":"DATE_PLACEHOLDER"}]}}}`,
+			wantUpcoming: true,
+			wantPremiere: true,
+		},
+		{
+			name:         "scheduled premiere in Japanese",
+			renderer:     `{"videoId":"c","upcomingEventData":{"startTime":"1700000000","upcomingEventText":{"simpleText":"プレミア公開予定: DATE_PLACEHOLDER"}}}`,
+			wantUpcoming: true,
+			wantPremiere: true,
+		},
+		{
+			name:     "live stream",
+			renderer: `{"videoId":"d","badges":[{"metadataBadgeRenderer":{"style":"BADGE_STYLE_TYPE_LIVE_NOW","label":"LIVE"}}]}`,
+			wantLive: true,
+		},
+		{
+			name:         "premiering now",
+			renderer:     `{"videoId":"e","badges":[{"metadataBadgeRenderer":{"style":"BADGE_STYLE_TYPE_LIVE_NOW","label":"PREMIERING NOW"}}]}`,
+			wantLive:     true,
+			wantPremiere: true,
+		},
+		{
+			name:     "past stream",
+			renderer: `{"videoId":"f","thumbnailOverlays":[{"thumbnailOverlayTimeStatusRenderer":{"style":"DEFAULT","text":{"simpleText":"1:02:03"}}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		var r ytVideoRenderer
+		if err := json.Unmarshal([]byte(tt.renderer), &r); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := r.IsLive(); got != tt.wantLive {
+			t.Errorf("%s: IsLive() = %v, want %v", tt.name, got, tt.wantLive)
+		}
+		if got := r.IsUpcoming(); got != tt.wantUpcoming {
+			t.Errorf("%s: IsUpcoming() = %v, want %v", tt.name, got, tt.wantUpcoming)
+		}
+		if got := r.IsPremiere(); got != tt.wantPremiere {
+			t.Errorf("%s: IsPremiere() = %v, want %v", tt.name, got, tt.wantPremiere)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// youtubeBrowseURL 页面“加载更多”时请求的 InnerTube browse 接口
+const youtubeBrowseURL = "https://www.youtube.com/youtubei/v1/browse"
+
+// youtubeDefaultClientVersion 页面中没有 ytcfg 时使用的 WEB 客户端版本
+const youtubeDefaultClientVersion = "2.20240101.00.00"
+
+var (
+	youtubeAPIKeyRegex        = regexp.MustCompile(`"INNERTUBE_API_KEY":"([^"]+)"`)
+	youtubeClientVersionRegex = regexp.MustCompile(`"INNERTUBE_CLIENT_VERSION":"([^"]+)"`)
+)
+
+// youtubeInnertubeClient 页面 ytcfg 中的 InnerTube 参数，后续的 browse 请求需要与页面一致
+type youtubeInnertubeClient struct {
+	APIKey        string
+	ClientVersion string
+}
+
+// parse 从页面脚本中读取 ytcfg 的 API key 和客户端版本，已读取的值不会被覆盖
+func (c *youtubeInnertubeClient) parse(script string) {
+	if c.APIKey == "" {
+		if matches := youtubeAPIKeyRegex.FindStringSubmatch(script); len(matches) > 1 {
+			c.APIKey = matches[1]
+		}
+	}
+	if c.ClientVersion == "" {
+		if matches := youtubeClientVersionRegex.FindStringSubmatch(script); len(matches) > 1 {
+			c.ClientVersion = matches[1]
+		}
+	}
+}
+
+// browse 按 continuation token 请求下一页并解码到 v
+func (c *youtubeInnertubeClient) browse(ctx context.Context, locale YouTubeLocale, continuation string, v any) error {
+	if err := waitForURL(ctx, youtubeBrowseURL); err != nil {
+		return err
+	}
+	version := c.ClientVersion
+	if version == "" {
+		version = youtubeDefaultClientVersion
+	}
+	body := map[string]any{
+		"context": map[string]any{
+			"client": map[string]any{
+				"clientName":    "WEB",
+				"clientVersion": version,
+				"hl":            locale.HL,
+				"gl":            locale.GL,
+			},
+		},
+		"continuation": continuation,
+	}
+
+	req := locale.newRequest(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-YouTube-Client-Name", "1").
+		SetHeader("X-YouTube-Client-Version", version).
+		SetQueryParam("prettyPrint", "false").
+		SetBody(body)
+	if c.APIKey != "" {
+		req.SetQueryParam("key", c.APIKey)
+	}
+	resp, err := req.Post(youtubeBrowseURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to request %s", youtubeBrowseURL)
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("error status code %d for %s", resp.StatusCode(), youtubeBrowseURL)
+	}
+	return errors.Wrapf(json.Unmarshal(resp.Body(), v), "failed to decode %s response", youtubeBrowseURL)
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/state"
+	"azuserver/lib/workpool"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const ServiceNameYouTubeLive = "YouTube Live"
+
+// youtubeLiveStateName 直播通知状态的文件名
+const youtubeLiveStateName = "youtube_live"
+
+// youtubeLiveStateTTL 超过该时间未再出现的直播从状态中移除
+const youtubeLiveStateTTL = 30 * 24 * time.Hour
+
+// YouTubeStreamStatus 直播的状态
+type YouTubeStreamStatus string
+
+const (
+	YouTubeStreamStatusUpcoming YouTubeStreamStatus = "upcoming"
+	YouTubeStreamStatusLive     YouTubeStreamStatus = "live"
+)
+
+// YouTubeStreamInfo 一场预定中或正在进行的直播（或首映）
+type YouTubeStreamInfo struct {
+	VideoID        string              `json:"videoId"`
+	Title          string              `json:"title"`
+	Status         YouTubeStreamStatus `json:"status"`
+	Premiere       bool                `json:"premiere"`
+	ScheduledStart time.Time           `json:"scheduledStart"`
+	VideoURL       string              `json:"videoUrl"`
+	ThumbnailURL   string              `json:"thumbnailUrl"`
+}
+
+// YouTubeLiveReport 某个频道的直播
+type YouTubeLiveReport struct {
+	ChannelID   string              `json:"channelId"`
+	ChannelName string              `json:"channelName"`
+	ChannelURL  string              `json:"channelUrl"`
+	Streams     []YouTubeStreamInfo `json:"streams"`
+}
+
+// youtubeLiveStateEntry 一场直播已发送过的通知
+type youtubeLiveStateEntry struct {
+	Scheduled bool      `json:"scheduled"`
+	Live      bool      `json:"live"`
+	SeenAt    time.Time `json:"seenAt"`
+}
+
+// youtubeLiveState 频道ID → 视频ID → 通知状态
+type youtubeLiveState map[string]map[string]youtubeLiveStateEntry
+
+func fetchYouTubePage(ctx context.Context, pageURL string, locale YouTubeLocale) (string, error) {
+	if err := waitForURL(ctx, pageURL); err != nil {
+		return "", err
+	}
+	resp, err := locale.newRequest(ctx).Get(locale.URL(pageURL))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to visit YouTube page: %s", pageURL)
+	}
+	if resp.StatusCode() != 200 {
+		return "", fmt.Errorf("error status code %d for %s", resp.StatusCode(), pageURL)
+	}
+	return resp.String(), nil
+}
+
+// FetchYouTubeChannelStreams 从频道的 /live 和 /streams 页面获取预定中和正在进行的直播
+func FetchYouTubeChannelStreams(ctx context.Context, channelID string, locale YouTubeLocale) (*YouTubeLiveReport, error) {
+	channelURL := youtubeChannelURL(channelID)
+	report := &YouTubeLiveReport{
+		ChannelID:  channelID,
+		ChannelURL: channelURL,
+	}
+	index := make(map[string]int)
+	add := func(stream YouTubeStreamInfo) {
+		if i, ok := index[stream.VideoID]; ok {
+			// 两个页面状态不一致时以正在直播为准
+			if stream.Status == YouTubeStreamStatusLive {
+				report.Streams[i].Status = YouTubeStreamStatusLive
+			}
+			return
+		}
+		index[stream.VideoID] = len(report.Streams)
+		report.Streams = append(report.Streams, stream)
+	}
+
+	// /live 会重定向到当前直播或最近预定的直播，没有时返回频道主页
+	livePage, liveErr := fetchYouTubePage(ctx, channelURL+"/live", locale)
+	if liveErr == nil {
+		var player ytPlayerResponse
+		found, err := extractYtInitialPlayerResponse(livePage, &player)
+		if err != nil {
+			slog.Warn("Failed to parse YouTube live page", "channelID", channelID, "error", err)
+		}
+		details := player.VideoDetails
+		if found && details.VideoID != "" && details.ChannelID == channelID {
+			report.ChannelName = details.Author
+			status := YouTubeStreamStatus("")
+			if player.IsLive() {
+				status = YouTubeStreamStatusLive
+			} else if details.IsUpcoming {
+				status = YouTubeStreamStatusUpcoming
+			}
+			if status != "" {
+				add(YouTubeStreamInfo{
+					VideoID:        details.VideoID,
+					Title:          details.Title,
+					Status:         status,
+					Premiere:       !details.IsLiveContent,
+					ScheduledStart: player.ScheduledStart(),
+					VideoURL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", details.VideoID),
+					ThumbnailURL:   fmt.Sprintf("https://img.youtube.com/vi/%s/maxresdefault.jpg", details.VideoID),
+				})
+			}
+		}
+	}
+
+	streamsPage, streamsErr := fetchYouTubePage(ctx, channelURL+"/streams", locale)
+	if streamsErr == nil {
+		var data ytBrowseData
+		if _, err := extractYtInitialData(streamsPage, &data); err != nil {
+			streamsErr = err
+		}
+		if name := data.Metadata.ChannelMetadataRenderer.Title; name != "" {
+			report.ChannelName = name
+		}
+		for _, renderer := range data.SelectedTab().VideoRenderers() {
+			status := YouTubeStreamStatus("")
+			if renderer.IsLive() {
+				status = YouTubeStreamStatusLive
+			} else if renderer.IsUpcoming() {
+				status = YouTubeStreamStatusUpcoming
+			}
+			if renderer.VideoID == "" || status == "" {
+				continue
+			}
+			add(YouTubeStreamInfo{
+				VideoID:        renderer.VideoID,
+				Title:          renderer.Title.String(),
+				Status:         status,
+				Premiere:       renderer.IsPremiere(),
+				ScheduledStart: renderer.ScheduledStart(),
+				VideoURL:       fmt.Sprintf("https://www.youtube.com/watch?v=%s", renderer.VideoID),
+				ThumbnailURL:   renderer.Thumbnail.Largest(),
+			})
+		}
+	}
+
+	if liveErr != nil && streamsErr != nil {
+		return nil, errors.Wrapf(streamsErr, "failed to fetch YouTube streams for %s", channelID)
+	}
+	return report, nil
+}
+
+// SendYouTubeLive 检查频道列表中的直播，每场直播的预定和开播各通知一次
+func SendYouTubeLive(ctx context.Context, channels []string) error {
+	if len(channels) == 0 {
+		return errors.New("no YouTube channels to watch for live streams")
+	}
+
+	store := state.NewStore(config.GetDataDir())
+	notified := youtubeLiveState{}
+	if _, err := store.Load(youtubeLiveStateName, &notified); err != nil {
+		return err
+	}
+
+	// 并发获取各频道的直播，之后按顺序比对和发送
+	locale := YouTubeLocaleForTask(AzutvTaskTypeYouTubeLive)
+	reports := make([]*YouTubeLiveReport, len(channels))
+	errs := workpool.Run(ctx, len(channels), fetchWorkers, func(ctx context.Context, i int) error {
+		channelID, err := ResolveYouTubeChannelID(ctx, channels[i])
+		if err != nil {
+			return err
+		}
+		reports[i], err = FetchYouTubeChannelStreams(ctx, channelID, locale)
+		return err
+	})
+
+	var failed int
+	for i, channel := range channels {
+		err := errs[i]
+		if err == nil {
+			err = checkYouTubeChannelStreams(reports[i], notified)
+		}
+		if err != nil {
+			failed++
+			slog.Warn("Failed to check YouTube channel streams", "channel", channel, "error", err)
+		}
+	}
+
+	if !IsDryRun() {
+		if err := store.Save(youtubeLiveStateName, notified); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to check %d of %d YouTube channels", failed, len(channels))
+	}
+	return nil
+}
+
+func checkYouTubeChannelStreams(report *YouTubeLiveReport, notified youtubeLiveState) error {
+	channelID := report.ChannelID
+	now := time.Now()
+	entries := make(map[string]youtubeLiveStateEntry)
+	for videoID, entry := range notified[channelID] {
+		if now.Sub(entry.SeenAt) < youtubeLiveStateTTL {
+			entries[videoID] = entry
+		}
+	}
+
+	var pending []YouTubeStreamInfo
+	for _, stream := range report.Streams {
+		entry := entries[stream.VideoID]
+		entry.SeenAt = now
+		switch {
+		case stream.Status == YouTubeStreamStatusLive && !entry.Live:
+			pending = append(pending, stream)
+		case stream.Status == YouTubeStreamStatusUpcoming && !entry.Scheduled:
+			pending = append(pending, stream)
+		}
+		entries[stream.VideoID] = entry
+	}
+
+	if len(pending) > 0 {
+		report.Streams = pending
+		items := make([]history.Item, 0, len(pending))
+		for idx, stream := range pending {
+			id := stream.VideoID + ":" + string(stream.Status)
+			items = append(items, newHistoryItem(id, idx+1, stream.Title, stream.VideoURL, report.ChannelName, stream))
+		}
+		if err := publish(publication{
+			Task:     AzutvTaskTypeYouTubeLive,
+			Variant:  channelID,
+			Username: ServiceNameYouTubeLive,
+			Items:    items,
+			Data:     report,
+		}); err != nil {
+			// 只更新出现时间，未发送的通知下次运行时重试
+			notified[channelID] = entries
+			return err
+		}
+
+		// 开播时不再补发预定通知
+		for _, stream := range pending {
+			entry := entries[stream.VideoID]
+			entry.Scheduled = true
+			if stream.Status == YouTubeStreamStatusLive {
+				entry.Live = true
+			}
+			entries[stream.VideoID] = entry
+		}
+	}
+
+	notified[channelID] = entries
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"azuserver/config"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/gocolly/colly"
+)
+
+// YouTubeLocale YouTube 请求的界面语言（hl）和地区（gl），决定页面中计数、相对时间等文本的语言
+type YouTubeLocale struct {
+	HL string
+	GL string
+}
+
+// YouTubeLocaleForTask 返回任务配置的界面语言和地区
+func YouTubeLocaleForTask(task AzutvTaskType) YouTubeLocale {
+	hl, gl := config.GetYouTubeLocale(string(task))
+	return YouTubeLocale{HL: hl, GL: gl}
+}
+
+// URL 在地址上加上 hl、gl 参数
+func (l YouTubeLocale) URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set("hl", l.HL)
+	query.Set("gl", l.GL)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// AcceptLanguage 返回对应的 Accept-Language 请求头，如 "ja-JP,ja;q=0.9"
+func (l YouTubeLocale) AcceptLanguage() string {
+	lang := l.HL
+	if !strings.Contains(lang, "-") && l.GL != "" {
+		lang = l.HL + "-" + l.GL
+	}
+	base, _, _ := strings.Cut(l.HL, "-")
+	return fmt.Sprintf("%s,%s;q=0.9", lang, base)
+}
+
+// headers 返回请求 YouTube 时统一使用的请求头，PREF Cookie 与 hl/gl 参数保持一致
+func (l YouTubeLocale) headers() map[string]string {
+	return map[string]string{
+		"User-Agent":      youtubeUserAgent,
+		"Accept-Language": l.AcceptLanguage(),
+		"Cookie":          fmt.Sprintf("PREF=hl=%s&gl=%s", l.HL, l.GL),
+	}
+}
+
+// newCollector 创建带有统一请求头、请求绑定到 ctx 的 colly 采集器
+func (l YouTubeLocale) newCollector(ctx context.Context) *colly.Collector {
+	c := colly.NewCollector(
+		colly.UserAgent(youtubeUserAgent),
+	)
+	bindCollector(ctx, c)
+	headers := l.headers()
+	c.OnRequest(func(r *colly.Request) {
+		for key, value := range headers {
+			r.Headers.Set(key, value)
+		}
+	})
+	return c
+}
+
+// newRequest 创建带有统一请求头的 resty 请求
+func (l YouTubeLocale) newRequest(ctx context.Context) *resty.Request {
+	return resty.New().R().
+		SetContext(ctx).
+		SetHeaders(l.headers())
+}
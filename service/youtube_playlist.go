@@ -0,0 +1,306 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/state"
+	"azuserver/lib/workpool"
+	"azuserver/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/pkg/errors"
+)
+
+const ServiceNameYouTubePlaylist = "YouTube Playlist"
+
+// youtubePlaylistStateName 播放列表内容的状态文件名
+const youtubePlaylistStateName = "youtube_playlist"
+
+// youtubePlaylistMaxPages 最多获取的页数，每页 100 个视频，播放列表最多 5000 个视频
+const youtubePlaylistMaxPages = 50
+
+var youtubePlaylistIDRegex = regexp.MustCompile(`^[0-9A-Za-z_-]{10,}$`)
+
+// YouTubePlaylistItem 播放列表中的一个视频
+type YouTubePlaylistItem struct {
+	VideoID     string `json:"videoId"`
+	Title       string `json:"title"`
+	ChannelName string `json:"channelName"`
+	ChannelID   string `json:"channelId"`
+	Position    int    `json:"position"`
+	Duration    string `json:"duration"`
+	// 第一次发现该视频在列表中的时间，YouTube 页面不提供公开的加入时间
+	AddedAt  time.Time `json:"addedAt"`
+	VideoURL string    `json:"videoUrl"`
+}
+
+// YouTubePlaylist 播放列表当前的内容
+type YouTubePlaylist struct {
+	PlaylistID string                `json:"playlistId"`
+	Title      string                `json:"title"`
+	Owner      string                `json:"owner"`
+	URL        string                `json:"url"`
+	Total      int64                 `json:"total"`
+	Items      []YouTubePlaylistItem `json:"items"`
+
+	totalKnown bool // Total 是否从页面的统计文本中解析出来
+}
+
+// Complete 判断是否已获取播放列表中的全部视频，无法解析视频总数时视为不完整
+func (p *YouTubePlaylist) Complete() bool {
+	return p.totalKnown && int64(len(p.Items)) >= p.Total
+}
+
+// YouTubePlaylistReport 播放列表的变化，第一次运行时 Added 为完整列表
+type YouTubePlaylistReport struct {
+	PlaylistID string                `json:"playlistId"`
+	Title      string                `json:"title"`
+	Owner      string                `json:"owner"`
+	URL        string                `json:"url"`
+	Total      int64                 `json:"total"`
+	Initial    bool                  `json:"initial"`
+	Added      []YouTubePlaylistItem `json:"added"`
+	Removed    []YouTubePlaylistItem `json:"removed"`
+}
+
+// youtubePlaylistState 播放列表ID → 视频ID → 上次看到的条目
+type youtubePlaylistState map[string]map[string]YouTubePlaylistItem
+
+// parseYouTubePlaylistID 支持直接的播放列表ID或带 list= 参数的链接
+func parseYouTubePlaylistID(playlist string) (string, error) {
+	playlist = strings.TrimSpace(playlist)
+	if u, err := url.Parse(playlist); err == nil && u.Host != "" {
+		playlist = u.Query().Get("list")
+	}
+	if !youtubePlaylistIDRegex.MatchString(playlist) {
+		return "", errors.Errorf("invalid YouTube playlist ID %q", playlist)
+	}
+	return playlist, nil
+}
+
+// FetchYouTubePlaylist 从播放列表页面的 ytInitialData 获取视频列表，页面首次加载最多 100 个，
+// 其余的按 continuation 通过 browse 接口逐页获取
+func FetchYouTubePlaylist(ctx context.Context, playlistID string, locale YouTubeLocale) (*YouTubePlaylist, error) {
+	playlistURL := fmt.Sprintf("https://www.youtube.com/playlist?list=%s", playlistID)
+	if err := waitForURL(ctx, playlistURL); err != nil {
+		return nil, err
+	}
+
+	c := locale.newCollector(ctx)
+
+	playlist := &YouTubePlaylist{
+		PlaylistID: playlistID,
+		URL:        playlistURL,
+	}
+	var found bool
+	var parseErr error
+	var continuation string
+	var client youtubeInnertubeClient
+	c.OnHTML("script", func(e *colly.HTMLElement) {
+		client.parse(e.Text)
+		if found || !strings.Contains(e.Text, "ytInitialData") {
+			return
+		}
+
+		var data ytPlaylistData
+		ok, err := extractYtInitialData(e.Text, &data)
+		if !ok {
+			return
+		}
+		if err != nil {
+			parseErr = err
+			return
+		}
+		found = true
+
+		playlist.Title = data.Metadata.PlaylistMetadataRenderer.Title
+		playlist.Owner = data.Owner()
+		if stats := data.Stats(); len(stats) > 0 {
+			playlist.Total, playlist.totalKnown = utils.ParseCount(stats[0])
+		}
+		playlist.appendRenderers(data.VideoRenderers())
+		continuation = data.Continuation()
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		slog.Error("YouTube playlist scraping error", "url", r.Request.URL, "error", err)
+	})
+
+	if err := c.Visit(locale.URL(playlistURL)); err != nil {
+		return nil, errors.Wrapf(err, "failed to visit YouTube playlist: %s", playlistURL)
+	}
+	if parseErr != nil {
+		return nil, errors.Wrapf(parseErr, "failed to parse YouTube playlist: %s", playlistURL)
+	}
+	if !found {
+		return nil, errors.Errorf("no ytInitialData in YouTube playlist: %s", playlistURL)
+	}
+
+	// 后续页面获取失败时返回已加载的部分，Complete 为 false，不会误判视频被移除
+	for page := 1; continuation != "" && page < youtubePlaylistMaxPages; page++ {
+		var next ytPlaylistContinuation
+		if err := client.browse(ctx, locale, continuation, &next); err != nil {
+			slog.Warn("Failed to load more YouTube playlist items",
+				"playlistID", playlistID, "loaded", len(playlist.Items), "error", err)
+			break
+		}
+		renderers := next.VideoRenderers()
+		if len(renderers) == 0 {
+			break
+		}
+		playlist.appendRenderers(renderers)
+		continuation = next.Continuation()
+	}
+	return playlist, nil
+}
+
+// appendRenderers 将一页播放列表视频追加到 Items，页面未给出序号时按加载顺序编号
+func (p *YouTubePlaylist) appendRenderers(renderers []*ytPlaylistVideoRenderer) {
+	for _, renderer := range renderers {
+		if renderer.VideoID == "" {
+			continue
+		}
+		item := YouTubePlaylistItem{
+			VideoID:  renderer.VideoID,
+			Title:    renderer.Title.String(),
+			Position: len(p.Items) + 1,
+			Duration: renderer.LengthText.String(),
+			VideoURL: fmt.Sprintf("https://www.youtube.com/watch?v=%s&list=%s", renderer.VideoID, p.PlaylistID),
+		}
+		if position, err := strconv.Atoi(renderer.Index.String()); err == nil {
+			item.Position = position
+		}
+		if runs := renderer.ShortBylineText.Runs; len(runs) > 0 {
+			item.ChannelName = runs[0].Text
+			item.ChannelID = runs[0].NavigationEndpoint.BrowseEndpoint.BrowseID
+		}
+		p.Items = append(p.Items, item)
+	}
+}
+
+// SendYouTubePlaylist 检查播放列表，第一次运行时发送完整列表，之后只发送新增和移除的视频
+func SendYouTubePlaylist(ctx context.Context, playlists []string) error {
+	if len(playlists) == 0 {
+		return errors.New("no YouTube playlists to track")
+	}
+
+	store := state.NewStore(config.GetDataDir())
+	known := youtubePlaylistState{}
+	if _, err := store.Load(youtubePlaylistStateName, &known); err != nil {
+		return err
+	}
+
+	// 并发获取各播放列表，之后按顺序比对和发送
+	locale := YouTubeLocaleForTask(AzutvTaskTypeYouTubePlaylist)
+	fetched := make([]*YouTubePlaylist, len(playlists))
+	errs := workpool.Run(ctx, len(playlists), fetchWorkers, func(ctx context.Context, i int) error {
+		playlistID, err := parseYouTubePlaylistID(playlists[i])
+		if err != nil {
+			return err
+		}
+		fetched[i], err = FetchYouTubePlaylist(ctx, playlistID, locale)
+		return err
+	})
+
+	var failed int
+	for i, playlist := range playlists {
+		err := errs[i]
+		if err == nil {
+			err = checkYouTubePlaylist(fetched[i], known)
+		}
+		if err != nil {
+			failed++
+			slog.Warn("Failed to check YouTube playlist", "playlist", playlist, "error", err)
+		}
+	}
+
+	if !IsDryRun() {
+		if err := store.Save(youtubePlaylistStateName, known); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to check %d of %d YouTube playlists", failed, len(playlists))
+	}
+	return nil
+}
+
+func checkYouTubePlaylist(playlist *YouTubePlaylist, known youtubePlaylistState) error {
+	previous, tracked := known[playlist.PlaylistID]
+	now := time.Now()
+
+	report := &YouTubePlaylistReport{
+		PlaylistID: playlist.PlaylistID,
+		Title:      playlist.Title,
+		Owner:      playlist.Owner,
+		URL:        playlist.URL,
+		Total:      playlist.Total,
+		Initial:    !tracked,
+	}
+
+	current := make(map[string]YouTubePlaylistItem, len(playlist.Items))
+	for _, item := range playlist.Items {
+		if old, ok := previous[item.VideoID]; ok {
+			item.AddedAt = old.AddedAt
+		} else {
+			item.AddedAt = now
+			report.Added = append(report.Added, item)
+		}
+		current[item.VideoID] = item
+	}
+
+	// 只加载了部分视频时无法判断未出现的视频是否被移除，保留其记录
+	if playlist.Complete() {
+		for _, item := range previous {
+			if _, ok := current[item.VideoID]; !ok {
+				report.Removed = append(report.Removed, item)
+			}
+		}
+		sort.Slice(report.Removed, func(i, j int) bool {
+			return report.Removed[i].Position < report.Removed[j].Position
+		})
+	} else {
+		slog.Info("YouTube playlist only partially loaded, skipping removal check",
+			"playlistID", playlist.PlaylistID, "loaded", len(playlist.Items), "total", playlist.Total)
+		for videoID, item := range previous {
+			if _, ok := current[videoID]; !ok {
+				current[videoID] = item
+			}
+		}
+	}
+
+	if len(report.Added) == 0 && len(report.Removed) == 0 {
+		known[playlist.PlaylistID] = current
+		return nil
+	}
+
+	items := make([]history.Item, 0, len(report.Added)+len(report.Removed))
+	for _, item := range report.Added {
+		items = append(items, newHistoryItem(item.VideoID, item.Position, item.Title, item.VideoURL, item.ChannelName, item))
+	}
+	for _, item := range report.Removed {
+		items = append(items, newHistoryItem(item.VideoID+":removed", item.Position, item.Title, item.VideoURL, item.ChannelName, item))
+	}
+	if err := publish(publication{
+		Task:     AzutvTaskTypeYouTubePlaylist,
+		Variant:  playlist.PlaylistID,
+		Username: ServiceNameYouTubePlaylist,
+		Items:    items,
+		Data:     report,
+	}); err != nil {
+		return err
+	}
+
+	// 发送成功后才更新记录，失败的变化下次运行时重试
+	known[playlist.PlaylistID] = current
+	return nil
+}
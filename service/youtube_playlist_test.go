@@ -0,0 +1,232 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckYouTubePlaylist(t *testing.T) {
+	// 试运行时 publish 将任务数据以 JSON 打印到标准输出，用它读取发送的变化
+	if err := SetOutputMode(string(OutputModeJSON)); err != nil {
+		t.Fatal(err)
+	}
+	defer SetOutputMode(string(OutputModeDiscord))
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	addedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := func(id string, position int) YouTubePlaylistItem {
+		return YouTubePlaylistItem{VideoID: id, Title: "video " + id, Position: position}
+	}
+	tracked := func(ids ...string) map[string]YouTubePlaylistItem {
+		items := make(map[string]YouTubePlaylistItem)
+		for i, id := range ids {
+			it := item(id, i+1)
+			it.AddedAt = addedAt
+			items[id] = it
+		}
+		return items
+	}
+
+	tests := []struct {
+		name        string
+		known       youtubePlaylistState
+		items       []YouTubePlaylistItem
+		total       int64
+		totalKnown  bool
+		wantPublish bool
+		wantInitial bool
+		wantAdded   []string
+		wantRemoved []string
+		wantState   []string
+	}{
+		{
+			name:        "first run",
+			known:       youtubePlaylistState{},
+			items:       []YouTubePlaylistItem{item("a", 1), item("b", 2)},
+			total:       2,
+			totalKnown:  true,
+			wantPublish: true,
+			wantInitial: true,
+			wantAdded:   []string{"a", "b"},
+			wantState:   []string{"a", "b"},
+		},
+		{
+			name:        "added item",
+			known:       youtubePlaylistState{"PL": tracked("a", "b")},
+			items:       []YouTubePlaylistItem{item("a", 1), item("b", 2), item("c", 3)},
+			total:       3,
+			totalKnown:  true,
+			wantPublish: true,
+			wantAdded:   []string{"c"},
+			wantState:   []string{"a", "b", "c"},
+		},
+		{
+			name:        "removed item",
+			known:       youtubePlaylistState{"PL": tracked("a", "b", "c")},
+			items:       []YouTubePlaylistItem{item("a", 1), item("c", 2)},
+			total:       2,
+			totalKnown:  true,
+			wantPublish: true,
+			wantRemoved: []string{"b"},
+			wantState:   []string{"a", "c"},
+		},
+		{
+			name:       "unchanged",
+			known:      youtubePlaylistState{"PL": tracked("a", "b")},
+			items:      []YouTubePlaylistItem{item("a", 1), item("b", 2)},
+			total:      2,
+			totalKnown: true,
+			wantState:  []string{"a", "b"},
+		},
+		{
+			// 只加载了部分视频时，未出现的视频可能在后面的页面中，不能当作已移除
+			name:       "partial load",
+			known:      youtubePlaylistState{"PL": tracked("a", "b", "c")},
+			items:      []YouTubePlaylistItem{item("a", 1)},
+			total:      3,
+			totalKnown: true,
+			wantState:  []string{"a", "b", "c"},
+		},
+		{
+			name:        "partial load with new item",
+			known:       youtubePlaylistState{"PL": tracked("a", "b", "c")},
+			items:       []YouTubePlaylistItem{item("d", 1), item("a", 2)},
+			total:       4,
+			totalKnown:  true,
+			wantPublish: true,
+			wantAdded:   []string{"d"},
+			wantState:   []string{"a", "b", "c", "d"},
+		},
+		{
+			name:      "unknown total",
+			known:     youtubePlaylistState{"PL": tracked("a", "b")},
+			items:     []YouTubePlaylistItem{item("a", 1)},
+			wantState: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		if err := out.Truncate(0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := out.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		playlist := &YouTubePlaylist{
+			PlaylistID: "PL",
+			Total:      tt.total,
+			Items:      tt.items,
+			totalKnown: tt.totalKnown,
+		}
+		if err := checkYouTubePlaylist(playlist, tt.known); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if _, err := out.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		printed, err := io.ReadAll(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if published := len(printed) > 0; published != tt.wantPublish {
+			t.Errorf("%s: published = %v, want %v", tt.name, published, tt.wantPublish)
+		}
+		if len(printed) > 0 {
+			var p struct {
+				Data YouTubePlaylistReport `json:"data"`
+			}
+			if err := json.Unmarshal(printed, &p); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if p.Data.Initial != tt.wantInitial {
+				t.Errorf("%s: Initial = %v, want %v", tt.name, p.Data.Initial, tt.wantInitial)
+			}
+			if got := playlistItemIDs(p.Data.Added); !reflect.DeepEqual(got, tt.wantAdded) {
+				t.Errorf("%s: Added = %v, want %v", tt.name, got, tt.wantAdded)
+			}
+			if got := playlistItemIDs(p.Data.Removed); !reflect.DeepEqual(got, tt.wantRemoved) {
+				t.Errorf("%s: Removed = %v, want %v", tt.name, got, tt.wantRemoved)
+			}
+		}
+
+		state := tt.known["PL"]
+		var ids []string
+		for _, id := range []string{"a", "b", "c", "d"} {
+			if _, ok := state[id]; ok {
+				ids = append(ids, id)
+			}
+		}
+		if !reflect.DeepEqual(ids, tt.wantState) || len(state) != len(tt.wantState) {
+			t.Errorf("%s: state = %v, want %v", tt.name, ids, tt.wantState)
+		}
+		// 已记录的视频保留第一次发现的时间
+		if a, ok := state["a"]; ok && len(tt.known) > 0 && !tt.wantInitial && !a.AddedAt.Equal(addedAt) {
+			t.Errorf("%s: AddedAt of a = %v, want %v", tt.name, a.AddedAt, addedAt)
+		}
+	}
+}
+
+func playlistItemIDs(items []YouTubePlaylistItem) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.VideoID)
+	}
+	return ids
+}
+
+func TestYtPlaylistContinuation(t *testing.T) {
+	page := `{"contents":{"twoColumnBrowseResultsRenderer":{"tabs":[{"tabRenderer":{"content":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"playlistVideoListRenderer":{"contents":[
+		{"playlistVideoRenderer":{"videoId":"a","index":{"simpleText":"1"}}},
+		{"playlistVideoRenderer":{"videoId":"b","index":{"simpleText":"2"}}},
+		{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"page2"}}}}
+	]}}]}}]}}}}]}}}`
+	var data ytPlaylistData
+	if err := json.Unmarshal([]byte(page), &data); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(data.VideoRenderers()); got != 2 {
+		t.Errorf("first page videos = %d, want 2", got)
+	}
+	if got := data.Continuation(); got != "page2" {
+		t.Errorf("first page continuation = %q, want %q", got, "page2")
+	}
+
+	next := `{"onResponseReceivedActions":[{"appendContinuationItemsAction":{"continuationItems":[
+		{"playlistVideoRenderer":{"videoId":"c"}}
+	]}}]}`
+	var cont ytPlaylistContinuation
+	if err := json.Unmarshal([]byte(next), &cont); err != nil {
+		t.Fatal(err)
+	}
+	if got := cont.Continuation(); got != "" {
+		t.Errorf("last page continuation = %q, want empty", got)
+	}
+
+	playlist := &YouTubePlaylist{PlaylistID: "PL", Total: 3, totalKnown: true}
+	playlist.appendRenderers(data.VideoRenderers())
+	playlist.appendRenderers(cont.VideoRenderers())
+	if got := playlistItemIDs(playlist.Items); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("items = %v, want [a b c]", got)
+	}
+	// 后续页面没有序号时按加载顺序编号
+	if got := playlist.Items[2].Position; got != 3 {
+		t.Errorf("position of c = %d, want 3", got)
+	}
+	if !playlist.Complete() {
+		t.Error("Complete() = false after loading all pages")
+	}
+	playlist.totalKnown = false
+	if playlist.Complete() {
+		t.Error("Complete() = true with unknown total")
+	}
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
-	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/workpool"
+	"azuserver/utils"
+	"context"
 	"fmt"
 	"log/slog"
 	"regexp"
@@ -17,53 +20,132 @@ const ServiceNameYouTubeUser = "YouTube User Info"
 
 // YouTubeUserInfo 存储用户基本信息
 type YouTubeUserInfo struct {
-	UserID        string
-	ChannelName   string
-	SubscriberCount string
-	VideoCount    string
-	ViewCount     string
-	Description   string
-	AvatarURL     string
-	ChannelURL    string
+	UserID          string `json:"userId"`
+	ChannelID       string `json:"channelId"`
+	ChannelName     string `json:"channelName"`
+	SubscriberCount string `json:"subscriberCount"`
+	VideoCount      string `json:"videoCount"`
+	ViewCount       string `json:"viewCount"`
+	Description     string `json:"description"`
+	AvatarURL       string `json:"avatarUrl"`
+	ChannelURL      string `json:"channelUrl"`
+	// 由上面的显示文本解析出的数值，无法解析时为0
+	Subscribers int64 `json:"subscribers"`
+	Videos      int64 `json:"videos"`
+	Views       int64 `json:"views"`
+}
+
+// YouTubeVideoKind 视频的类型，对应频道页面的标签页
+type YouTubeVideoKind string
+
+const (
+	YouTubeVideoKindVideo  YouTubeVideoKind = "video"
+	YouTubeVideoKindShort  YouTubeVideoKind = "short"
+	YouTubeVideoKindStream YouTubeVideoKind = "stream"
+)
+
+// youtubeVideoKindTabs 各类型视频所在的频道标签页
+var youtubeVideoKindTabs = map[YouTubeVideoKind]string{
+	YouTubeVideoKindVideo:  "videos",
+	YouTubeVideoKindShort:  "shorts",
+	YouTubeVideoKindStream: "streams",
+}
+
+// ParseYouTubeVideoKinds 将标签页名称列表（videos、shorts、streams）转换为视频类型
+func ParseYouTubeVideoKinds(sections []string) ([]YouTubeVideoKind, error) {
+	var kinds []YouTubeVideoKind
+	for _, section := range sections {
+		found := false
+		for kind, tab := range youtubeVideoKindTabs {
+			if section == tab || section == string(kind) {
+				kinds = append(kinds, kind)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("unknown YouTube section %q, expected videos, shorts or streams", section)
+		}
+	}
+	return kinds, nil
+}
+
+// filterYouTubeVideos 返回指定类型的视频，未标注类型的视为普通视频
+func filterYouTubeVideos(kind YouTubeVideoKind, videos []YouTubeVideoInfo) []YouTubeVideoInfo {
+	var filtered []YouTubeVideoInfo
+	for _, video := range videos {
+		videoKind := video.Kind
+		if videoKind == "" {
+			videoKind = YouTubeVideoKindVideo
+		}
+		if videoKind == kind {
+			filtered = append(filtered, video)
+		}
+	}
+	return filtered
 }
 
 // YouTubeVideoInfo 存储视频信息
 type YouTubeVideoInfo struct {
-	VideoID     string
-	Title       string
-	ViewCount   string
-	LikeCount   string
-	UploadDate  string
-	Duration    string
-	Description string
-	ThumbnailURL string
-	VideoURL    string
+	Kind         YouTubeVideoKind `json:"kind"`
+	VideoID      string           `json:"videoId"`
+	Title        string           `json:"title"`
+	ViewCount    string           `json:"viewCount"`
+	LikeCount    string           `json:"likeCount"`
+	UploadDate   string           `json:"uploadDate"`
+	Duration     string           `json:"duration"`
+	Description  string           `json:"description"`
+	ThumbnailURL string           `json:"thumbnailUrl"`
+	VideoURL     string           `json:"videoUrl"`
+	// 由 ViewCount、LikeCount 解析出的数值，无法解析时为0
+	Views int64 `json:"views"`
+	Likes int64 `json:"likes"`
+	// 发布时间，相对时间（"3 days ago"）按获取时刻换算，无法解析时为零值
+	PublishedAt time.Time `json:"publishedAt"`
 }
 
-// GetYouTubeUserInfo 根据用户ID或频道ID获取YouTube用户信息
-func GetYouTubeUserInfo(userID string) (*YouTubeUserInfo, error) {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
+// parseCounts 解析显示文本中的订阅数、视频数和观看次数
+func (u *YouTubeUserInfo) parseCounts() {
+	u.Subscribers, _ = utils.ParseCount(u.SubscriberCount)
+	u.Videos, _ = utils.ParseCount(u.VideoCount)
+	u.Views, _ = utils.ParseCount(u.ViewCount)
+}
 
-	userInfo := &YouTubeUserInfo{
-		UserID: userID,
+// parseCounts 解析显示文本中的观看次数和点赞数，已有数值时保留
+func (v *YouTubeVideoInfo) parseCounts() {
+	if v.Views == 0 {
+		v.Views, _ = utils.ParseCount(v.ViewCount)
+	}
+	if v.Likes == 0 {
+		v.Likes, _ = utils.ParseCount(v.LikeCount)
 	}
+}
+
+// YouTubeUserReport 用户信息及其最新视频
+type YouTubeUserReport struct {
+	User   *YouTubeUserInfo   `json:"user"`
+	Videos []YouTubeVideoInfo `json:"videos"`
+}
+
+// GetYouTubeUserInfo 根据用户ID或频道ID获取YouTube用户信息
+func GetYouTubeUserInfo(ctx context.Context, userID string, locale YouTubeLocale) (*YouTubeUserInfo, error) {
+	c := locale.newCollector(ctx)
 
-	// 处理不同格式的用户ID
-	var channelURL string
-	if strings.HasPrefix(userID, "UC") && len(userID) == 24 {
-		// 频道ID格式 (UCxxxxxxxxxxxxxxxxxxxx)
-		channelURL = fmt.Sprintf("https://www.youtube.com/channel/%s", userID)
-	} else if strings.HasPrefix(userID, "@") {
-		// 新格式用户名 (@username)
-		channelURL = fmt.Sprintf("https://www.youtube.com/%s", userID)
-	} else {
-		// 传统用户名或其他格式
-		channelURL = fmt.Sprintf("https://www.youtube.com/c/%s", userID)
+	// @handle、自定义名称等统一解析为频道ID，使用固定的 /channel/ 地址
+	channelID, err := ResolveYouTubeChannelID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	channelURL := youtubeChannelURL(channelID)
+	if err := waitForURL(ctx, channelURL); err != nil {
+		return nil, err
 	}
 
-	userInfo.ChannelURL = channelURL
+	userInfo := &YouTubeUserInfo{
+		UserID:     userID,
+		ChannelID:  channelID,
+		ChannelURL: channelURL,
+	}
 
 	// 获取频道基本信息
 	c.OnHTML("meta[name='description']", func(e *colly.HTMLElement) {
@@ -86,7 +168,7 @@ func GetYouTubeUserInfo(userID string) (*YouTubeUserInfo, error) {
 	// 获取订阅数、视频数等信息（通过页面脚本获取）
 	c.OnHTML("script", func(e *colly.HTMLElement) {
 		scriptContent := e.Text
-		
+
 		// 尝试提取订阅数
 		if strings.Contains(scriptContent, "subscriberCountText") {
 			re := regexp.MustCompile(`"subscriberCountText":\{"simpleText":"([^"]+)"`)
@@ -110,88 +192,107 @@ func GetYouTubeUserInfo(userID string) (*YouTubeUserInfo, error) {
 		slog.Error("YouTube scraping error", "url", r.Request.URL, "error", err)
 	})
 
-	err := c.Visit(channelURL)
+	err = c.Visit(locale.URL(channelURL))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to visit YouTube channel: %s", channelURL)
 	}
+	userInfo.parseCounts()
 
 	return userInfo, nil
 }
 
 // GetYouTubeUserVideos 获取用户最新的视频列表
-func GetYouTubeUserVideos(userID string, limit int) ([]YouTubeVideoInfo, error) {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
+func GetYouTubeUserVideos(ctx context.Context, userID string, limit int, locale YouTubeLocale) ([]YouTubeVideoInfo, error) {
+	return GetYouTubeChannelVideos(ctx, userID, YouTubeVideoKindVideo, limit, locale)
+}
+
+// GetYouTubeChannelVideos 从频道对应的标签页（/videos、/shorts、/streams）获取指定类型的最新视频
+func GetYouTubeChannelVideos(ctx context.Context, userID string, kind YouTubeVideoKind, limit int, locale YouTubeLocale) ([]YouTubeVideoInfo, error) {
+	tab, ok := youtubeVideoKindTabs[kind]
+	if !ok {
+		return nil, errors.Errorf("unknown YouTube video kind %q", kind)
+	}
+
+	c := locale.newCollector(ctx)
 
 	var videos []YouTubeVideoInfo
-	
-	// 构建视频列表页面URL
-	var videosURL string
-	if strings.HasPrefix(userID, "UC") && len(userID) == 24 {
-		videosURL = fmt.Sprintf("https://www.youtube.com/channel/%s/videos", userID)
-	} else if strings.HasPrefix(userID, "@") {
-		videosURL = fmt.Sprintf("https://www.youtube.com/%s/videos", userID)
-	} else {
-		videosURL = fmt.Sprintf("https://www.youtube.com/c/%s/videos", userID)
+
+	channelID, err := ResolveYouTubeChannelID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	videosURL := youtubeChannelURL(channelID) + "/" + tab
+	if err := waitForURL(ctx, videosURL); err != nil {
+		return nil, err
 	}
+	fetchedAt := time.Now()
 
-	// 通过script标签获取视频信息
+	// 解析 ytInitialData 中标签页的 richItemRenderer → videoRenderer / Shorts
+	var parseErr error
 	c.OnHTML("script", func(e *colly.HTMLElement) {
-		scriptContent := e.Text
-		
-		// 使用正则表达式提取视频信息
-		if strings.Contains(scriptContent, "ytInitialData") {
-			// 提取视频ID
-			videoIDRegex := regexp.MustCompile(`"videoId":"([^"]+)"`)
-			videoIDs := videoIDRegex.FindAllStringSubmatch(scriptContent, limit)
-			
-			// 提取视频标题
-			titleRegex := regexp.MustCompile(`"title":\{"runs":\[\{"text":"([^"]+)"`)
-			titles := titleRegex.FindAllStringSubmatch(scriptContent, limit)
-			
-			// 提取视频观看次数
-			viewRegex := regexp.MustCompile(`"shortViewCountText":\{"simpleText":"([^"]+)"`)
-			views := viewRegex.FindAllStringSubmatch(scriptContent, limit)
-			
-			// 提取发布时间
-			publishRegex := regexp.MustCompile(`"publishedTimeText":\{"simpleText":"([^"]+)"`)
-			publishTimes := publishRegex.FindAllStringSubmatch(scriptContent, limit)
-
-			// 组合视频信息
-			maxLen := len(videoIDs)
-			if len(titles) < maxLen {
-				maxLen = len(titles)
-			}
-			if limit > 0 && maxLen > limit {
-				maxLen = limit
-			}
+		if len(videos) > 0 || !strings.Contains(e.Text, "ytInitialData") {
+			return
+		}
 
-			for i := 0; i < maxLen; i++ {
-				video := YouTubeVideoInfo{}
-				
-				if i < len(videoIDs) && len(videoIDs[i]) > 1 {
-					video.VideoID = videoIDs[i][1]
-					video.VideoURL = fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.VideoID)
-					video.ThumbnailURL = fmt.Sprintf("https://img.youtube.com/vi/%s/maxresdefault.jpg", video.VideoID)
-				}
-				
-				if i < len(titles) && len(titles[i]) > 1 {
-					video.Title = titles[i][1]
-				}
-				
-				if i < len(views) && len(views[i]) > 1 {
-					video.ViewCount = views[i][1]
+		var data ytBrowseData
+		found, err := extractYtInitialData(e.Text, &data)
+		if !found {
+			return
+		}
+		if err != nil {
+			parseErr = err
+			return
+		}
+
+		if kind == YouTubeVideoKindShort {
+			for _, short := range data.SelectedTab().Shorts() {
+				if limit > 0 && len(videos) >= limit {
+					break
 				}
-				
-				if i < len(publishTimes) && len(publishTimes[i]) > 1 {
-					video.UploadDate = publishTimes[i][1]
+				if short.VideoID == "" {
+					continue
 				}
+				views, _ := utils.ParseCount(short.ViewCount)
+				videos = append(videos, YouTubeVideoInfo{
+					Kind:         kind,
+					VideoID:      short.VideoID,
+					Title:        short.Title,
+					ViewCount:    short.ViewCount,
+					Views:        views,
+					VideoURL:     fmt.Sprintf("https://www.youtube.com/shorts/%s", short.VideoID),
+					ThumbnailURL: fmt.Sprintf("https://img.youtube.com/vi/%s/hqdefault.jpg", short.VideoID),
+				})
+			}
+			return
+		}
 
-				if video.VideoID != "" {
-					videos = append(videos, video)
-				}
+		for _, renderer := range data.SelectedTab().VideoRenderers() {
+			if limit > 0 && len(videos) >= limit {
+				break
+			}
+			if renderer.VideoID == "" {
+				continue
 			}
+			// 正在进行和预定的直播由 youtube_live 通知，这里只列出已结束的直播
+			if kind == YouTubeVideoKindStream && (renderer.IsLive() || renderer.IsUpcoming()) {
+				continue
+			}
+			// 完整的观看次数比缩写更精确
+			views, _ := utils.ParseCount(renderer.ViewCountText.String())
+			publishedAt, _ := utils.ParseRelativeTime(renderer.PublishedTimeText.String(), fetchedAt)
+			videos = append(videos, YouTubeVideoInfo{
+				Kind:         kind,
+				VideoID:      renderer.VideoID,
+				Title:        renderer.Title.String(),
+				ViewCount:    renderer.ShortViewCountText.String(),
+				Views:        views,
+				PublishedAt:  publishedAt,
+				UploadDate:   renderer.PublishedTimeText.String(),
+				Duration:     renderer.LengthText.String(),
+				Description:  renderer.DescriptionSnippet.String(),
+				VideoURL:     fmt.Sprintf("https://www.youtube.com/watch?v=%s", renderer.VideoID),
+				ThumbnailURL: fmt.Sprintf("https://img.youtube.com/vi/%s/maxresdefault.jpg", renderer.VideoID),
+			})
 		}
 	})
 
@@ -199,25 +300,29 @@ func GetYouTubeUserVideos(userID string, limit int) ([]YouTubeVideoInfo, error)
 		slog.Error("YouTube videos scraping error", "url", r.Request.URL, "error", err)
 	})
 
-	err := c.Visit(videosURL)
+	err = c.Visit(locale.URL(videosURL))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to visit YouTube videos page: %s", videosURL)
 	}
+	if len(videos) == 0 && parseErr != nil {
+		return nil, errors.Wrapf(parseErr, "failed to parse YouTube videos page: %s", videosURL)
+	}
 
 	return videos, nil
 }
 
 // GetYouTubeVideoDetails 获取单个视频的详细信息（包括点赞数等）
-func GetYouTubeVideoDetails(videoID string) (*YouTubeVideoInfo, error) {
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
-
+func GetYouTubeVideoDetails(ctx context.Context, videoID string, locale YouTubeLocale) (*YouTubeVideoInfo, error) {
 	video := &YouTubeVideoInfo{
 		VideoID:      videoID,
 		VideoURL:     fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 		ThumbnailURL: fmt.Sprintf("https://img.youtube.com/vi/%s/maxresdefault.jpg", videoID),
 	}
+	if err := waitForURL(ctx, video.VideoURL); err != nil {
+		return nil, err
+	}
+
+	c := locale.newCollector(ctx)
 
 	// 获取视频标题
 	c.OnHTML("meta[property='og:title']", func(e *colly.HTMLElement) {
@@ -232,7 +337,7 @@ func GetYouTubeVideoDetails(videoID string) (*YouTubeVideoInfo, error) {
 	// 通过script获取详细信息
 	c.OnHTML("script", func(e *colly.HTMLElement) {
 		scriptContent := e.Text
-		
+
 		// 提取观看次数
 		if strings.Contains(scriptContent, "viewCount") {
 			re := regexp.MustCompile(`"viewCount":"([^"]+)"`)
@@ -269,6 +374,7 @@ func GetYouTubeVideoDetails(videoID string) (*YouTubeVideoInfo, error) {
 			matches := re.FindStringSubmatch(scriptContent)
 			if len(matches) > 1 {
 				video.UploadDate = matches[1]
+				video.PublishedAt = parsePublishDate(matches[1])
 			}
 		}
 	})
@@ -277,14 +383,26 @@ func GetYouTubeVideoDetails(videoID string) (*YouTubeVideoInfo, error) {
 		slog.Error("YouTube video details scraping error", "url", r.Request.URL, "error", err)
 	})
 
-	err := c.Visit(video.VideoURL)
+	err := c.Visit(locale.URL(video.VideoURL))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to visit YouTube video: %s", video.VideoURL)
 	}
+	video.parseCounts()
 
 	return video, nil
 }
 
+// parsePublishDate 解析视频页面的 publishDate，可能是日期或带时区的完整时间
+func parsePublishDate(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
 // formatDuration 将秒数转换为时分秒格式
 func formatDuration(seconds int) string {
 	hours := seconds / 3600
@@ -297,94 +415,79 @@ func formatDuration(seconds int) string {
 	return fmt.Sprintf("%d:%02d", minutes, secs)
 }
 
-// FormatYouTubeUserMessage 格式化YouTube用户信息为消息
+// FormatYouTubeUserMessage 用默认模板将YouTube用户信息格式化为消息
 func FormatYouTubeUserMessage(userInfo *YouTubeUserInfo, videos []YouTubeVideoInfo) []string {
-	var messages []string
-	var message strings.Builder
-
-	// 用户基本信息
-	message.WriteString(fmt.Sprintf("# YouTube 用户信息\n"))
-	message.WriteString(fmt.Sprintf("**频道名称**: %s\n", userInfo.ChannelName))
-	message.WriteString(fmt.Sprintf("**用户ID**: %s\n", userInfo.UserID))
-	message.WriteString(fmt.Sprintf("**订阅数**: %s\n", userInfo.SubscriberCount))
-	message.WriteString(fmt.Sprintf("**视频总数**: %s\n", userInfo.VideoCount))
-	message.WriteString(fmt.Sprintf("**频道链接**: %s\n", userInfo.ChannelURL))
-	if userInfo.Description != "" {
-		message.WriteString(fmt.Sprintf("**简介**: %s\n", userInfo.Description))
-	}
-	message.WriteString("\n")
-
-	messages = append(messages, message.String())
-	message.Reset()
-
-	// 最新视频信息
-	if len(videos) > 0 {
-		message.WriteString("## 最新视频\n")
-		for idx, video := range videos {
-			if idx >= 10 { // 限制显示数量
-				break
-			}
-			message.WriteString(fmt.Sprintf("### %d. [%s](%s)\n", idx+1, video.Title, video.VideoURL))
-			if video.ViewCount != "" {
-				message.WriteString(fmt.Sprintf("**观看次数**: %s\n", video.ViewCount))
-			}
-			if video.LikeCount != "" {
-				message.WriteString(fmt.Sprintf("**点赞数**: %s\n", video.LikeCount))
-			}
-			if video.UploadDate != "" {
-				message.WriteString(fmt.Sprintf("**发布时间**: %s\n", video.UploadDate))
-			}
-			if video.Duration != "" {
-				message.WriteString(fmt.Sprintf("**时长**: %s\n", video.Duration))
-			}
-			message.WriteString("\n")
-
-			// 每5个视频分一条消息
-			if (idx+1)%5 == 0 {
-				messages = append(messages, message.String())
-				message.Reset()
-			}
-		}
-
-		if left := message.String(); left != "" {
-			messages = append(messages, left)
-		}
-	}
-
-	return messages
+	return renderDefaultMessages(AzutvTaskTypeYouTubeUser, YouTubeUserReport{User: userInfo, Videos: videos})
 }
 
-// SendYouTubeUserInfo 获取并发送YouTube用户信息到Discord
-func SendYouTubeUserInfo(userID string) error {
+// SendYouTubeUserInfo 获取并发送YouTube用户信息到Discord，kinds 为要列出的视频类型，为空时只列出普通视频
+func SendYouTubeUserInfo(ctx context.Context, userID string, kinds []YouTubeVideoKind) error {
+	locale := YouTubeLocaleForTask(AzutvTaskTypeYouTubeUser)
+
 	// 获取用户信息
-	userInfo, err := GetYouTubeUserInfo(userID)
+	userInfo, err := GetYouTubeUserInfo(ctx, userID, locale)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get YouTube user info for %s", userID)
 	}
 
-	// 获取最新视频
-	videos, err := GetYouTubeUserVideos(userID, 10)
-	if err != nil {
-		slog.Warn("Failed to get YouTube videos", "userID", userID, "error", err)
-		videos = []YouTubeVideoInfo{} // 继续处理，但没有视频信息
+	// 获取各类型的最新视频，每种类型单独限量，避免 Shorts 挤掉普通视频
+	if len(kinds) == 0 {
+		kinds = []YouTubeVideoKind{YouTubeVideoKindVideo}
+	}
+	videos := []YouTubeVideoInfo{}
+	for _, kind := range kinds {
+		kindVideos, err := GetYouTubeChannelVideos(ctx, userID, kind, 10, locale)
+		if err != nil {
+			// 继续处理，但没有该类型的视频信息
+			slog.Warn("Failed to get YouTube videos", "userID", userID, "kind", kind, "error", err)
+			continue
+		}
+		videos = append(videos, kindVideos...)
 	}
 
-	// 获取视频详细信息（包括点赞数）
-	for i := range videos {
-		if videoDetails, err := GetYouTubeVideoDetails(videos[i].VideoID); err == nil {
-			videos[i].LikeCount = videoDetails.LikeCount
-			videos[i].Duration = videoDetails.Duration
-			if videos[i].UploadDate == "" {
-				videos[i].UploadDate = videoDetails.UploadDate
-			}
+	// 并发获取视频详细信息（包括点赞数），按主机限速
+	errs := workpool.Run(ctx, len(videos), fetchWorkers, func(ctx context.Context, i int) error {
+		videoDetails, err := GetYouTubeVideoDetails(ctx, videos[i].VideoID, locale)
+		if err != nil {
+			return err
+		}
+		videos[i].LikeCount = videoDetails.LikeCount
+		videos[i].Likes = videoDetails.Likes
+		// 详情页的观看次数是精确值
+		if videoDetails.Views > 0 {
+			videos[i].Views = videoDetails.Views
+		}
+		videos[i].Duration = videoDetails.Duration
+		if videos[i].UploadDate == "" {
+			videos[i].UploadDate = videoDetails.UploadDate
+		}
+		// 带时区的完整发布时间比相对时间精确，只有日期时保留相对时间的换算结果
+		precise := len(videoDetails.UploadDate) > len("2006-01-02")
+		if !videoDetails.PublishedAt.IsZero() && (precise || videos[i].PublishedAt.IsZero()) {
+			videos[i].PublishedAt = videoDetails.PublishedAt
 		}
-		// 添加延迟避免被限制
-		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			slog.Warn("Failed to get YouTube video details", "videoID", videos[i].VideoID, "error", err)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	// 格式化消息
-	messages := FormatYouTubeUserMessage(userInfo, videos)
+	items := make([]history.Item, 0, len(videos))
+	for idx, video := range videos {
+		items = append(items, newHistoryItem(video.VideoID, idx+1, video.Title, video.VideoURL, userInfo.ChannelName, video))
+	}
 
-	// 发送到Discord
-	return SendMessageToDiscord(messages, config.GetDiscordChatWebhookUrl(), ServiceNameYouTubeUser)
+	// 记录历史并发送到Discord
+	return publish(publication{
+		Task:     AzutvTaskTypeYouTubeUser,
+		Variant:  userInfo.ChannelID,
+		Username: ServiceNameYouTubeUser,
+		Items:    items,
+		Data:     YouTubeUserReport{User: userInfo, Videos: videos},
+	})
 }
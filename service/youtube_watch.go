@@ -0,0 +1,215 @@
+package service
+
+import (
+	"azuserver/config"
+	"azuserver/lib/history"
+	"azuserver/lib/state"
+	"azuserver/lib/workpool"
+	"azuserver/utils"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+const ServiceNameYouTubeWatch = "YouTube Uploads"
+
+// youtubeWatchStateName 已通知视频的状态文件名
+const youtubeWatchStateName = "youtube_watch"
+
+// youtubeWatchMaxSeen 每个频道保留的已通知视频ID数量，需大于RSS中的条目数（15）
+const youtubeWatchMaxSeen = 200
+
+// youtubeFeed 频道RSS（Atom）订阅
+type youtubeFeed struct {
+	Title  string `xml:"title"`
+	Author struct {
+		Name string `xml:"name"`
+		URI  string `xml:"uri"`
+	} `xml:"author"`
+	Entries []youtubeFeedEntry `xml:"entry"`
+}
+
+type youtubeFeedEntry struct {
+	VideoID   string    `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+	ChannelID string    `xml:"http://www.youtube.com/xml/schemas/2015 channelId"`
+	Title     string    `xml:"title"`
+	Published time.Time `xml:"published"`
+	Group     struct {
+		Description string `xml:"http://search.yahoo.com/mrss/ description"`
+		Thumbnail   struct {
+			URL string `xml:"url,attr"`
+		} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		Community struct {
+			Statistics struct {
+				Views string `xml:"views,attr"`
+			} `xml:"http://search.yahoo.com/mrss/ statistics"`
+		} `xml:"http://search.yahoo.com/mrss/ community"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// YouTubeWatchReport 某个频道本次发现的新视频
+type YouTubeWatchReport struct {
+	ChannelID   string             `json:"channelId"`
+	ChannelName string             `json:"channelName"`
+	ChannelURL  string             `json:"channelUrl"`
+	Videos      []YouTubeVideoInfo `json:"videos"`
+}
+
+// youtubeWatchState 每个频道已通知过的视频ID，最新的在前
+type youtubeWatchState map[string][]string
+
+// FetchYouTubeChannelFeed 通过公开的频道RSS获取最新上传的视频
+func FetchYouTubeChannelFeed(ctx context.Context, channelID string) (*YouTubeWatchReport, error) {
+	feedURL := fmt.Sprintf("https://www.youtube.com/feeds/videos.xml?channel_id=%s", channelID)
+	if err := waitForURL(ctx, feedURL); err != nil {
+		return nil, err
+	}
+	resp, err := resty.New().R().
+		SetContext(ctx).
+		SetHeader("User-Agent", youtubeUserAgent).
+		Get(feedURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch YouTube feed: %s", feedURL)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("error status code %d for %s", resp.StatusCode(), feedURL)
+	}
+
+	var feed youtubeFeed
+	if err := xml.Unmarshal(resp.Body(), &feed); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode YouTube feed: %s", feedURL)
+	}
+
+	report := &YouTubeWatchReport{
+		ChannelID:   channelID,
+		ChannelName: feed.Author.Name,
+		ChannelURL:  feed.Author.URI,
+	}
+	if report.ChannelName == "" {
+		report.ChannelName = feed.Title
+	}
+	for _, entry := range feed.Entries {
+		views, _ := utils.ParseCount(entry.Group.Community.Statistics.Views)
+		report.Videos = append(report.Videos, YouTubeVideoInfo{
+			VideoID:      entry.VideoID,
+			Title:        entry.Title,
+			ViewCount:    entry.Group.Community.Statistics.Views,
+			Views:        views,
+			UploadDate:   entry.Published.In(config.GetLocation()).Format("2006-01-02 15:04:05"),
+			PublishedAt:  entry.Published,
+			Description:  entry.Group.Description,
+			ThumbnailURL: entry.Group.Thumbnail.URL,
+			VideoURL:     fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.VideoID),
+		})
+	}
+	return report, nil
+}
+
+// SendYouTubeWatch 检查频道列表中的新视频并发送，每个视频只通知一次
+func SendYouTubeWatch(ctx context.Context, channels []string) error {
+	if len(channels) == 0 {
+		return errors.New("no YouTube channels to watch")
+	}
+
+	store := state.NewStore(config.GetDataDir())
+	seen := youtubeWatchState{}
+	if _, err := store.Load(youtubeWatchStateName, &seen); err != nil {
+		return err
+	}
+
+	// 并发获取各频道的 RSS，之后按顺序比对和发送
+	reports := make([]*YouTubeWatchReport, len(channels))
+	errs := workpool.Run(ctx, len(channels), fetchWorkers, func(ctx context.Context, i int) error {
+		channelID, err := ResolveYouTubeChannelID(ctx, channels[i])
+		if err != nil {
+			return err
+		}
+		reports[i], err = FetchYouTubeChannelFeed(ctx, channelID)
+		return err
+	})
+
+	var failed int
+	for i, channel := range channels {
+		err := errs[i]
+		if err == nil {
+			err = checkYouTubeChannelUploads(channel, reports[i], seen)
+		}
+		if err != nil {
+			failed++
+			slog.Warn("Failed to check YouTube channel uploads", "channel", channel, "error", err)
+		}
+	}
+
+	if !IsDryRun() {
+		if err := store.Save(youtubeWatchStateName, seen); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to check %d of %d YouTube channels", failed, len(channels))
+	}
+	return nil
+}
+
+func checkYouTubeChannelUploads(channel string, report *YouTubeWatchReport, seen youtubeWatchState) error {
+	channelID := report.ChannelID
+	known, watched := seen[channelID]
+	knownSet := make(map[string]bool, len(known))
+	for _, id := range known {
+		knownSet[id] = true
+	}
+
+	var newVideos []YouTubeVideoInfo
+	var newIDs []string
+	for _, video := range report.Videos {
+		if video.VideoID == "" || knownSet[video.VideoID] {
+			continue
+		}
+		newVideos = append(newVideos, video)
+		newIDs = append(newIDs, video.VideoID)
+	}
+
+	ids := append(newIDs, known...)
+	if len(ids) > youtubeWatchMaxSeen {
+		ids = ids[:youtubeWatchMaxSeen]
+	}
+
+	// 第一次监视的频道只记录现有视频，避免一次性推送整个列表
+	if !watched {
+		seen[channelID] = ids
+		slog.Info("Started watching YouTube channel", "channel", channel, "channelID", channelID, "videos", len(newIDs))
+		return nil
+	}
+	if len(newVideos) == 0 {
+		return nil
+	}
+
+	// RSS 中最新的在前，按发布顺序通知
+	for i, j := 0, len(newVideos)-1; i < j; i, j = i+1, j-1 {
+		newVideos[i], newVideos[j] = newVideos[j], newVideos[i]
+	}
+	report.Videos = newVideos
+
+	items := make([]history.Item, 0, len(newVideos))
+	for idx, video := range newVideos {
+		items = append(items, newHistoryItem(video.VideoID, idx+1, video.Title, video.VideoURL, report.ChannelName, video))
+	}
+	if err := publish(publication{
+		Task:     AzutvTaskTypeYouTubeWatch,
+		Variant:  channelID,
+		Username: ServiceNameYouTubeWatch,
+		Items:    items,
+		Data:     report,
+	}); err != nil {
+		return err
+	}
+
+	// 发送成功后才标记为已通知，失败的视频下次运行时重试
+	seen[channelID] = ids
+	return nil
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var countRegex = regexp.MustCompile(`(\d[\d,]*(?:\.\d+)?)\s*(?:((?i:[kmb]|thousand|million|billion))\b|([千万萬億亿]))?`)
+
+var countMultipliers = map[string]float64{
+	"k": 1e3, "thousand": 1e3, "千": 1e3,
+	"m": 1e6, "million": 1e6,
+	"b": 1e9, "billion": 1e9,
+	"万": 1e4, "萬": 1e4,
+	"億": 1e8, "亿": 1e8,
+}
+
+// ParseCount parses a display count such as "1,234 views", "1.23M subscribers",
+// "12万回視聴" or "3.4亿次观看". It reports false when s contains no number.
+func ParseCount(s string) (int64, bool) {
+	m := countRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	number := strings.ReplaceAll(m[1], ",", "")
+	unit := strings.ToLower(m[2] + m[3])
+
+	multiplier, ok := countMultipliers[unit]
+	if !ok {
+		if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+			return n, true
+		}
+		multiplier = 1
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(math.Round(f * multiplier)), true
+}
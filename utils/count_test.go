@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"1,234 views", 1234, true},
+		{"987", 987, true},
+		{"1.2K views", 1200, true},
+		{"1.2k views", 1200, true},
+		{"1.23M subscribers", 1230000, true},
+		{"1.23m subscribers", 1230000, true},
+		{"3B views", 3000000000, true},
+		{"3b views", 3000000000, true},
+		{"4.5 thousand views", 4500, true},
+		{"1.2 Million views", 1200000, true},
+		{"1.2 million views", 1200000, true},
+		{"2 BILLION views", 2000000000, true},
+		{"2 Billion views", 2000000000, true},
+		{"12万回視聴", 120000, true},
+		{"チャンネル登録者数 120万人", 1200000, true},
+		{"1.5億回視聴", 150000000, true},
+		{"3.4亿次观看", 340000000, true},
+		{"5.6万次观看", 56000, true},
+		{"12萬次觀看", 120000, true},
+		{"3千", 3000, true},
+		{"No views", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseCount(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCount(%q) = (%d, %v), want (%d, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
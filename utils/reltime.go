@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// relativeTimeRegex matches "3 days ago", "Streamed 2 hours ago", "3 日前", "1 か月前",
+// "2小时前", "3個月前" and "5분 전".
+var relativeTimeRegex = regexp.MustCompile(`(?i)\b(\d+|an?|one)\s*[かヶカケ个個]?\s*(seconds?|minutes?|hours?|days?|weeks?|months?|years?|秒|分|小?[時时]|日|天|[周週]|月|年|초|분|시간|일|주|개월|년)\S*\s*(ago|前|전)`)
+
+// ParseRelativeTime resolves a relative time such as "3 days ago" against now.
+// English, Japanese, Chinese and Korean strings are supported. Months and years
+// are calendar based, so the result is only as precise as the source text.
+func ParseRelativeTime(s string, now time.Time) (time.Time, bool) {
+	m := relativeTimeRegex.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		// "a day ago", "an hour ago", "one week ago"
+		n = 1
+	}
+
+	unit := strings.ToLower(m[2])
+	switch {
+	case strings.HasPrefix(unit, "second"), unit == "秒", unit == "초":
+		return now.Add(-time.Duration(n) * time.Second), true
+	case strings.HasPrefix(unit, "minute"), unit == "分", unit == "분":
+		return now.Add(-time.Duration(n) * time.Minute), true
+	case strings.HasPrefix(unit, "hour"), strings.HasSuffix(unit, "時"), strings.HasSuffix(unit, "时"), unit == "시간":
+		return now.Add(-time.Duration(n) * time.Hour), true
+	case strings.HasPrefix(unit, "day"), unit == "日", unit == "天", unit == "일":
+		return now.AddDate(0, 0, -n), true
+	case strings.HasPrefix(unit, "week"), unit == "周", unit == "週", unit == "주":
+		return now.AddDate(0, 0, -7*n), true
+	case strings.HasPrefix(unit, "month"), unit == "月", unit == "개월":
+		return now.AddDate(0, -n, 0), true
+	case strings.HasPrefix(unit, "year"), unit == "年", unit == "년":
+		return now.AddDate(-n, 0, 0), true
+	}
+	return time.Time{}, false
+}
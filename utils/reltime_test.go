@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRelativeTime(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"1 second ago", now.Add(-time.Second), true},
+		{"30 seconds ago", now.Add(-30 * time.Second), true},
+		{"1 minute ago", now.Add(-time.Minute), true},
+		{"5 minutes ago", now.Add(-5 * time.Minute), true},
+		{"an hour ago", now.Add(-time.Hour), true},
+		{"2 hours ago", now.Add(-2 * time.Hour), true},
+		{"1 day ago", now.AddDate(0, 0, -1), true},
+		{"3 days ago", now.AddDate(0, 0, -3), true},
+		{"a week ago", now.AddDate(0, 0, -7), true},
+		{"2 weeks ago", now.AddDate(0, 0, -14), true},
+		{"1 month ago", now.AddDate(0, -1, 0), true},
+		{"6 months ago", now.AddDate(0, -6, 0), true},
+		{"one year ago", now.AddDate(-1, 0, 0), true},
+		{"2 years ago", now.AddDate(-2, 0, 0), true},
+		{"3 Days Ago", now.AddDate(0, 0, -3), true},
+		{"Streamed 2 hours ago", now.Add(-2 * time.Hour), true},
+		{"Streamed 1 day ago", now.AddDate(0, 0, -1), true},
+		{"Premiered 5 days ago", now.AddDate(0, 0, -5), true},
+		{"3 日前", now.AddDate(0, 0, -3), true},
+		{"5 時間前", now.Add(-5 * time.Hour), true},
+		{"1 か月前", now.AddDate(0, -1, 0), true},
+		{"2 ヶ月前", now.AddDate(0, -2, 0), true},
+		{"1 年前", now.AddDate(-1, 0, 0), true},
+		{"10 分前に配信済み", now.Add(-10 * time.Minute), true},
+		{"2小时前", now.Add(-2 * time.Hour), true},
+		{"3天前", now.AddDate(0, 0, -3), true},
+		{"1周前", now.AddDate(0, 0, -7), true},
+		{"3个月前", now.AddDate(0, -3, 0), true},
+		{"3個月前", now.AddDate(0, -3, 0), true},
+		{"2週前", now.AddDate(0, 0, -14), true},
+		{"直播时间：5天前", now.AddDate(0, 0, -5), true},
+		{"5분 전", now.Add(-5 * time.Minute), true},
+		{"3시간 전", now.Add(-3 * time.Hour), true},
+		{"1일 전", now.AddDate(0, 0, -1), true},
+		{"2주 전", now.AddDate(0, 0, -14), true},
+		{"4개월 전", now.AddDate(0, -4, 0), true},
+		{"1년 전", now.AddDate(-1, 0, 0), true},
+		{"", time.Time{}, false},
+		{"Streamed live", time.Time{}, false},
+		{"Mar 3, 2024", time.Time{}, false},
+		{"3 days", time.Time{}, false},
+		{"in 3 days", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRelativeTime(tt.in, now)
+		if !got.Equal(tt.want) || ok != tt.ok {
+			t.Errorf("ParseRelativeTime(%q) = (%v, %v), want (%v, %v)", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -6,6 +6,17 @@ import (
 	"github.com/rivo/uniseg"
 )
 
+// SplitList splits a comma separated list, dropping empty items.
+func SplitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func WrapEveryNRunes(s string, n int) string {
 	runes := []rune(s)
 	var b strings.Builder